@@ -0,0 +1,194 @@
+// Package client reads the spot deals published by ec2-spot-finder, from
+// the static site or a serve instance, so Go programs don't each need to
+// parse spot_data.json themselves.
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Instance is an instance type's spot offer in a region
+type Instance struct {
+	InstanceType        string `json:"InstanceType"`
+	VCPUS               int    `json:"VCPUS"`
+	Memory              string `json:"Memory"` // e.g. "16 GiB"
+	SpotSavingRate      string `json:"SpotSavingRate"`
+	UpstreamSavingRate  string `json:"UpstreamSavingRate,omitempty"` // set when the upstream rate disagrees with the prices
+	SpotPrice           string `json:"SpotPrice"`                    // midpoint of SpotPriceRange when exact prices are withheld
+	SpotPriceRange      string `json:"SpotPriceRange,omitempty"`     // e.g. "0.0400-0.0500", set when exact prices are withheld
+	OnDemandPrice       string `json:"OnDemandPrice,omitempty"`
+	RecommendedMaxPrice string `json:"RecommendedMaxPrice,omitempty"`
+	CheaperThan         string `json:"CheaperThan,omitempty"`
+	InterruptionRate    string `json:"InterruptionRate,omitempty"`
+	Category            string `json:"Category,omitempty"`
+	Deprecated          bool   `json:"deprecated,omitempty"`
+	AtFloor             bool   `json:"at_floor,omitempty"`
+}
+
+// Price returns the hourly spot price in USD; when exact prices are withheld
+// it is the midpoint of SpotPriceRange
+func (i Instance) Price() float64 {
+	price, _ := strconv.ParseFloat(i.SpotPrice, 64)
+	return price
+}
+
+// MemoryGiB returns the memory size in GiB
+func (i Instance) MemoryGiB() float64 {
+	return leadingNumber(i.Memory)
+}
+
+// Savings returns the spot discount off the on-demand price in percent
+func (i Instance) Savings() float64 {
+	return leadingNumber(i.SpotSavingRate)
+}
+
+// Deal is one of the best deals across all regions
+type Deal struct {
+	InstanceType     string  `json:"instanceType"`
+	VCPUS            int     `json:"cpus"`
+	Memory           string  `json:"memory"`
+	SpotPrice        float64 `json:"price"`                // midpoint of PriceRange when exact prices are withheld
+	PriceRange       string  `json:"priceRange,omitempty"` // set when exact prices are withheld
+	PricePerVCPU     float64 `json:"pricePerVCPU"`
+	Region           string  `json:"region"`
+	InterruptionRate string  `json:"interruptionRate,omitempty"`
+}
+
+// Data is the published dataset
+type Data struct {
+	LastUpdated    string                `json:"last_updated"` // RFC 3339
+	Regions        map[string][]Instance `json:"regions"`
+	GlobalTop5     []Deal                `json:"global_top_5"`
+	Sources        map[string]string     `json:"sources,omitempty"`
+	RegionsUpdated map[string]string     `json:"regions_updated,omitempty"`
+	License        string                `json:"license,omitempty"`
+}
+
+// RegionInstance is an instance offer together with its region
+type RegionInstance struct {
+	Region string
+	Instance
+}
+
+// CheapestInRegion returns the instance with the lowest spot price in region
+func (d *Data) CheapestInRegion(region string) (Instance, bool) {
+	var cheapest Instance
+	found := false
+	for _, instance := range d.Regions[region] {
+		if instance.Price() <= 0 {
+			continue
+		}
+		if !found || instance.Price() < cheapest.Price() {
+			cheapest, found = instance, true
+		}
+	}
+	return cheapest, found
+}
+
+// TopByMemory returns up to n offers across all regions with the lowest
+// spot price per GiB of memory, for memory-bound workloads
+func (d *Data) TopByMemory(n int) []RegionInstance {
+	var offers []RegionInstance
+	for region, instances := range d.Regions {
+		for _, instance := range instances {
+			if instance.Price() > 0 && instance.MemoryGiB() > 0 {
+				offers = append(offers, RegionInstance{region, instance})
+			}
+		}
+	}
+	sort.Slice(offers, func(i, j int) bool {
+		a := offers[i].Price() / offers[i].MemoryGiB()
+		b := offers[j].Price() / offers[j].MemoryGiB()
+		if a != b {
+			return a < b
+		}
+		return offers[i].Region+offers[i].InstanceType < offers[j].Region+offers[j].InstanceType
+	})
+	if len(offers) > n {
+		offers = offers[:n]
+	}
+	return offers
+}
+
+// Client fetches the dataset and keeps the last copy, revalidating it with
+// the server's ETag or Last-Modified so unchanged data is not downloaded again
+type Client struct {
+	url        string
+	httpClient *http.Client
+
+	mu           sync.Mutex
+	data         *Data
+	etag         string
+	lastModified string
+}
+
+// New returns a client for the dataset at url: either a spot_data.json URL,
+// or the base URL of the site or a serve instance
+func New(url string, httpClient *http.Client) *Client {
+	if !strings.HasSuffix(url, ".json") {
+		url = strings.TrimSuffix(url, "/") + "/spot_data.json"
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{url: url, httpClient: httpClient}
+}
+
+// Fetch returns the current dataset, reusing the cached copy when the
+// server reports it unchanged. The returned Data must not be modified.
+func (c *Client) Fetch(ctx context.Context) (*Data, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.data != nil {
+		if c.etag != "" {
+			req.Header.Set("If-None-Match", c.etag)
+		}
+		if c.lastModified != "" {
+			req.Header.Set("If-Modified-Since", c.lastModified)
+		}
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && c.data != nil:
+		return c.data, nil
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("fetching %s: unexpected status %s", c.url, resp.Status)
+	}
+
+	var data Data
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", c.url, err)
+	}
+	c.data, c.etag, c.lastModified = &data, resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+	return c.data, nil
+}
+
+// leadingNumber parses the number at the start of values such as
+// "32 GiB" or "67%", returning zero when there is none
+func leadingNumber(s string) float64 {
+	s = strings.TrimSpace(s)
+	end := 0
+	for end < len(s) && (s[end] == '.' || (s[end] >= '0' && s[end] <= '9')) {
+		end++
+	}
+	value, _ := strconv.ParseFloat(s[:end], 64)
+	return value
+}
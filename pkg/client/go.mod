@@ -0,0 +1,3 @@
+module github.com/fjcloud/ec2-spot-finder-static/pkg/client
+
+go 1.20
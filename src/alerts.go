@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Alerts configures the price-drop notifications sent by the serve daemon
+type Alerts struct {
+	// MinDropPercent and Webhooks define a single level when Levels is empty
+	MinDropPercent float64  `json:"min_drop_percent"`
+	Webhooks       []string `json:"webhooks"`
+	// Levels escalate larger drops to other targets; each drop is sent to
+	// the highest level whose threshold it reaches
+	Levels []AlertLevel `json:"levels"`
+	// Cooldown suppresses repeated alerts for the same instance in a region
+	Cooldown duration `json:"cooldown"`
+	// DigestInterval is the minimum time between two notifications; alerts
+	// raised in between are collapsed into the next one
+	DigestInterval duration `json:"digest_interval"`
+	// DigestSize caps the drops listed in a notification, largest first
+	DigestSize int `json:"digest_size"`
+}
+
+// AlertLevel is a severity of price drops and the webhooks notified of them.
+// Webhooks receive a JSON POST with a Slack-compatible text field.
+type AlertLevel struct {
+	Name           string   `json:"name"`
+	MinDropPercent float64  `json:"min_drop_percent"`
+	Webhooks       []string `json:"webhooks"`
+}
+
+// alerts is loaded from the alerts file next to the config
+var alerts Alerts
+
+// alertMu serializes updates of the alert state
+var alertMu sync.Mutex
+
+// alertState is kept next to the served data so suppression survives restarts
+type alertState struct {
+	LastSent time.Time              `json:"last_sent,omitempty"`
+	Alerted  map[string]alertRecord `json:"alerted"`        // last alert per region/instance type
+	Held     []PriceChange          `json:"held,omitempty"` // waiting for quiet hours or the digest interval to end
+}
+
+// alertRecord is when an instance was last alerted and at which level
+type alertRecord struct {
+	At    time.Time `json:"at"`
+	Level string    `json:"level"`
+}
+
+// validate checks the alert thresholds, turning the single-level settings
+// into a level and ordering the levels by threshold
+func (a *Alerts) validate() error {
+	if len(a.Levels) == 0 {
+		a.Levels = []AlertLevel{{Name: "info", MinDropPercent: a.MinDropPercent, Webhooks: a.Webhooks}}
+	}
+	sort.SliceStable(a.Levels, func(i, j int) bool { return a.Levels[i].MinDropPercent < a.Levels[j].MinDropPercent })
+	seen := make(map[string]bool)
+	for _, level := range a.Levels {
+		if level.Name == "" || seen[level.Name] {
+			return fmt.Errorf("alert levels need unique names, got %q", level.Name)
+		}
+		seen[level.Name] = true
+		if level.MinDropPercent <= 0 || level.MinDropPercent >= 100 {
+			return fmt.Errorf("level %s: min_drop_percent must be between 0 and 100, got %v", level.Name, level.MinDropPercent)
+		}
+	}
+	if a.Cooldown < 0 || a.DigestInterval < 0 {
+		return fmt.Errorf("cooldown and digest_interval must not be negative")
+	}
+	if a.DigestSize <= 0 {
+		return fmt.Errorf("digest_size must be positive, got %d", a.DigestSize)
+	}
+	return nil
+}
+
+// enabled reports whether any level has a webhook to notify
+func (a Alerts) enabled() bool {
+	for _, level := range a.Levels {
+		if len(level.Webhooks) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// level returns the index of the highest level a price change reaches, or -1
+func (a Alerts) level(change PriceChange) int {
+	for i := len(a.Levels) - 1; i >= 0; i-- {
+		if change.ChangePercent <= -a.Levels[i].MinDropPercent {
+			return i
+		}
+	}
+	return -1
+}
+
+// levelIndex returns the index of the level called name, or -1
+func (a Alerts) levelIndex(name string) int {
+	for i, level := range a.Levels {
+		if level.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// priceDrops returns the changes in diff falling by at least minPercent
+func priceDrops(diff SpotDiff, minPercent float64) []PriceChange {
+	var drops []PriceChange
+	for _, change := range diff.Changed {
+		if change.ChangePercent <= -minPercent {
+			drops = append(drops, change)
+		}
+	}
+	return drops
+}
+
+// notifyPriceDrops alerts the webhooks of the price drops in diff, each at
+// its level. Drops of instances alerted within the cooldown are dropped
+// unless they escalate to a higher level; the rest are held during quiet
+// hours and until the digest interval has passed, then sent together with
+// the first refresh after.
+func notifyPriceDrops(diff SpotDiff, now time.Time, stateFile string) {
+	if !alerts.enabled() {
+		return
+	}
+
+	alertMu.Lock()
+	defer alertMu.Unlock()
+
+	state := alertState{Alerted: make(map[string]alertRecord)}
+	if err := readJSONFile(stateFile, &state); err != nil {
+		log.Printf("Error reading alert state, starting afresh: %v", err)
+	}
+	for key, record := range state.Alerted {
+		if now.Sub(record.At) >= time.Duration(alerts.Cooldown) {
+			delete(state.Alerted, key)
+		}
+	}
+
+	for _, drop := range priceDrops(diff, alerts.Levels[0].MinDropPercent) {
+		record, ok := state.Alerted[alertKey(drop)]
+		if !ok || alerts.level(drop) > alerts.levelIndex(record.Level) {
+			state.Held = holdAlert(state.Held, drop)
+		}
+	}
+
+	switch {
+	case len(state.Held) == 0:
+	case config.Schedule.quiet(now):
+		log.Printf("Holding %d price alerts until quiet hours end", len(state.Held))
+	case now.Sub(state.LastSent) < time.Duration(alerts.DigestInterval):
+		log.Printf("Holding %d price alerts for the next digest", len(state.Held))
+	default:
+		byLevel := make([][]PriceChange, len(alerts.Levels))
+		for _, drop := range state.Held {
+			// Merged flapping drops may have fallen below every level
+			if i := alerts.level(drop); i >= 0 {
+				byLevel[i] = append(byLevel[i], drop)
+				state.Alerted[alertKey(drop)] = alertRecord{now, alerts.Levels[i].Name}
+			}
+		}
+		for i, level := range alerts.Levels {
+			if len(byLevel[i]) == 0 {
+				continue
+			}
+			for _, webhook := range level.Webhooks {
+				if err := postAlert(webhook, level.Name, byLevel[i]); err != nil {
+					log.Printf("Error sending %s price alerts: %v", level.Name, err)
+				}
+			}
+		}
+		state.LastSent = now
+		state.Held = nil
+	}
+
+	if err := writeAlertState(stateFile, state); err != nil {
+		log.Printf("Error writing alert state: %v", err)
+	}
+}
+
+// alertKey identifies the instance a price alert is about
+func alertKey(change PriceChange) string {
+	return change.Region + "/" + change.InstanceType
+}
+
+// holdAlert adds a drop to the held alerts, replacing an older alert for the
+// same instance so a flapping price is reported once with its latest value
+func holdAlert(held []PriceChange, drop PriceChange) []PriceChange {
+	for i, alert := range held {
+		if alertKey(alert) == alertKey(drop) {
+			drop.OldPrice = alert.OldPrice
+			drop.ChangePercent = changePercent(drop.OldPrice, drop.NewPrice)
+			held[i] = drop
+			return held
+		}
+	}
+	return append(held, drop)
+}
+
+// writeAlertState replaces the state file atomically
+func writeAlertState(filename string, state alertState) error {
+	content, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	temp := filename + ".tmp"
+	if err := os.WriteFile(temp, append(content, '\n'), 0644); err != nil {
+		return err
+	}
+	return os.Rename(temp, filename)
+}
+
+// postAlert sends alerts to a webhook. Beyond the digest size only the
+// largest drops are listed, followed by a count of the others.
+func postAlert(webhook, level string, changes []PriceChange) error {
+	sorted := append([]PriceChange(nil), changes...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].ChangePercent < sorted[j].ChangePercent })
+
+	lines := []string{fmt.Sprintf("Spot price drops (%s):", level)}
+	for i, change := range sorted {
+		if i == alerts.DigestSize {
+			lines = append(lines, fmt.Sprintf("…and %d more", len(sorted)-i))
+			break
+		}
+		lines = append(lines, fmt.Sprintf("• %s in %s: $%s → $%s (%.1f%%)", change.InstanceType, change.Region, change.OldPrice, change.NewPrice, change.ChangePercent))
+	}
+	payload, err := json.Marshal(map[string]interface{}{"text": strings.Join(lines, "\n"), "level": level, "alerts": sorted})
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Post(webhook, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("webhook failed: %s: %s", resp.Status, msg)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+var comboASG = comboFlags.String("asg", "<ASG_NAME>", "name of the Auto Scaling group nomad and ecs output refers to")
+
+// AutoScalingGroupRequest is the input accepted by
+// aws autoscaling create-auto-scaling-group --cli-input-json
+type AutoScalingGroupRequest struct {
+	AutoScalingGroupName string               `json:"AutoScalingGroupName"`
+	MinSize              int                  `json:"MinSize"`
+	MaxSize              int                  `json:"MaxSize"`
+	DesiredCapacity      int                  `json:"DesiredCapacity"`
+	DesiredCapacityType  string               `json:"DesiredCapacityType"`
+	CapacityRebalance    bool                 `json:"CapacityRebalance"`
+	VPCZoneIdentifier    string               `json:"VPCZoneIdentifier"`
+	MixedInstancesPolicy MixedInstancesPolicy `json:"MixedInstancesPolicy"`
+}
+
+// MixedInstancesPolicy spreads an Auto Scaling group over the combo's types
+type MixedInstancesPolicy struct {
+	LaunchTemplate struct {
+		LaunchTemplateSpecification map[string]string `json:"LaunchTemplateSpecification"`
+		Overrides                   []ASGOverride     `json:"Overrides"`
+	} `json:"LaunchTemplate"`
+	InstancesDistribution map[string]interface{} `json:"InstancesDistribution"`
+}
+
+// ASGOverride is one instance type an Auto Scaling group may launch
+type ASGOverride struct {
+	InstanceType     string `json:"InstanceType"`
+	WeightedCapacity string `json:"WeightedCapacity"`
+}
+
+// ecsAutoScalingGroup renders a combo as the all-spot Auto Scaling group
+// behind an ECS capacity provider. Capacity is counted in vCPUs, and the
+// group may grow to twice the combo so managed scaling has headroom.
+func ecsAutoScalingGroup(combo Combo) ([]byte, error) {
+	request := AutoScalingGroupRequest{
+		AutoScalingGroupName: *comboASG,
+		MaxSize:              2 * combo.VCPUS,
+		DesiredCapacity:      combo.VCPUS,
+		DesiredCapacityType:  "vcpu",
+		CapacityRebalance:    true,
+		VPCZoneIdentifier:    strings.Join(fleetSubnets(), ","),
+	}
+	policy := &request.MixedInstancesPolicy
+	policy.LaunchTemplate.LaunchTemplateSpecification = map[string]string{
+		"LaunchTemplateName": *comboLaunchTemplate,
+		"Version":            "$Latest",
+	}
+	for _, pick := range combo.Picks {
+		policy.LaunchTemplate.Overrides = append(policy.LaunchTemplate.Overrides, ASGOverride{
+			InstanceType:     pick.Instance.InstanceType,
+			WeightedCapacity: fmt.Sprint(pick.Instance.VCPUS),
+		})
+	}
+	policy.InstancesDistribution = map[string]interface{}{
+		"OnDemandBaseCapacity":                0,
+		"OnDemandPercentageAboveBaseCapacity": 0,
+		"SpotAllocationStrategy":              "price-capacity-optimized",
+	}
+	return marshalFleetRequest(request)
+}
+
+// nomadScalingPolicy renders a combo as a Nomad Autoscaler cluster scaling
+// policy for the Auto Scaling group running the combo's types, followed by
+// a job constraint keeping allocations on those types
+func nomadScalingPolicy(combo Combo) ([]byte, error) {
+	var types []string
+	instances := 0
+	for _, pick := range combo.Picks {
+		types = append(types, pick.Instance.InstanceType)
+		instances += pick.Count
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Cluster scaling policy for %s: %d instances of %s\n", *comboASG, instances, strings.Join(types, ", "))
+	fmt.Fprintf(&b, "# for %d vCPUs at $%.4f/hour. Counts are instances, so the group must not weight its types.\n", combo.VCPUS, combo.HourlyCost)
+	b.WriteString("scaling \"spot_workers\" {\n")
+	b.WriteString("  enabled = true\n")
+	b.WriteString("  min     = 0\n")
+	fmt.Fprintf(&b, "  max     = %d\n\n", 2*instances)
+	b.WriteString("  policy {\n")
+	b.WriteString("    check \"cpu_allocated_percentage\" {\n")
+	b.WriteString("      source = \"nomad-apm\"\n")
+	b.WriteString("      query  = \"percentage-allocated_cpu\"\n\n")
+	b.WriteString("      strategy \"target-value\" {\n")
+	b.WriteString("        target = 70\n")
+	b.WriteString("      }\n")
+	b.WriteString("    }\n\n")
+	b.WriteString("    target \"aws-asg\" {\n")
+	fmt.Fprintf(&b, "      aws_asg_name        = %q\n", *comboASG)
+	b.WriteString("      node_class          = \"spot\"\n")
+	b.WriteString("      node_drain_deadline = \"2m\"\n")
+	b.WriteString("    }\n")
+	b.WriteString("  }\n")
+	b.WriteString("}\n\n")
+	b.WriteString("# Job constraint keeping allocations on the recommended types\n")
+	b.WriteString("constraint {\n")
+	b.WriteString("  attribute = \"${attr.platform.aws.instance-type}\"\n")
+	b.WriteString("  operator  = \"set_contains_any\"\n")
+	fmt.Fprintf(&b, "  value     = %q\n", strings.Join(types, ","))
+	b.WriteString("}")
+	return []byte(b.String()), nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// sourceSpotAdvisor credits the interruption rates, which enrich every source's instances
+const sourceSpotAdvisor = "aws-spot-advisor"
+
+// Attribution credits an upstream source of the published data, as some
+// upstream terms require
+type Attribution struct {
+	Source  string `json:"source"`
+	URL     string `json:"url,omitempty"`
+	License string `json:"license,omitempty"`
+	Notice  string `json:"notice"`
+}
+
+// defaultAttributions credit each source recorded in SpotData.Sources;
+// the attributions config setting overrides them per source
+var defaultAttributions = map[string]Attribution{
+	sourceEC2Shop: {
+		Source: "ec2.shop",
+		URL:    "https://ec2.shop",
+		Notice: "Spot and on-demand prices provided by ec2.shop.",
+	},
+	sourceAWSSpotFeed: {
+		Source: "Amazon Web Services",
+		URL:    "https://aws.amazon.com/ec2/spot/pricing/",
+		Notice: "Spot prices from the public Amazon EC2 Spot pricing page.",
+	},
+	sourceSpotAdvisor: {
+		Source: "Amazon Web Services",
+		URL:    "https://aws.amazon.com/ec2/spot/instance-advisor/",
+		Notice: "Interruption frequencies from the Amazon EC2 Spot Instance Advisor.",
+	},
+	sourceHetzner: {
+		Source: "Hetzner Online GmbH",
+		URL:    "https://www.hetzner.com/cloud",
+		Notice: "Server prices from the Hetzner Cloud API.",
+	},
+	sourceDigitalOcean: {
+		Source: "DigitalOcean, LLC",
+		URL:    "https://www.digitalocean.com/pricing/droplets",
+		Notice: "Droplet prices from the DigitalOcean API.",
+	},
+}
+
+// datasetAttributions credits the sources the dataset was built from, in a stable order
+func datasetAttributions(data SpotData) []Attribution {
+	sources := make(map[string]bool)
+	for _, source := range data.Sources {
+		sources[source] = true
+	}
+	for _, provider := range data.Providers {
+		for _, source := range provider.Sources {
+			sources[source] = true
+		}
+	}
+	for _, instances := range data.Regions {
+		for _, instance := range instances {
+			if instance.InterruptionRate != "" {
+				sources[sourceSpotAdvisor] = true
+				break
+			}
+		}
+	}
+
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var attributions []Attribution
+	for _, name := range names {
+		attribution, ok := config.Attributions[name]
+		if !ok {
+			attribution, ok = defaultAttributions[name]
+		}
+		if ok {
+			attributions = append(attributions, attribution)
+		}
+	}
+	return attributions
+}
+
+// attributionNotice renders attributions and the license as plain text for release notes
+func attributionNotice(license string, attributions []Attribution) string {
+	var b strings.Builder
+	for _, attribution := range attributions {
+		b.WriteString("- " + attribution.Notice)
+		if attribution.URL != "" {
+			b.WriteString(" " + attribution.URL)
+		}
+		b.WriteString("\n")
+	}
+	if license != "" {
+		b.WriteString("\nData license: " + license + "\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// awsSpotFeedURL serves the spot prices shown on the AWS spot pricing page
+var awsSpotFeedURL = "https://website.spot.ec2.aws.a2z.com/spot.js"
+
+// Sources recorded for each region in SpotData.Sources
+const (
+	sourceEC2Shop      = "ec2.shop"
+	sourceAWSSpotFeed  = "aws-spot-feed"
+	sourceHetzner      = "hetzner-api"
+	sourceDigitalOcean = "digitalocean-api"
+)
+
+// awsSpotFeedRegions maps the legacy region names still used by the feed to region codes
+var awsSpotFeedRegions = map[string]string{
+	"us-east":    "us-east-1",
+	"us-west":    "us-west-1",
+	"eu-ireland": "eu-west-1",
+	"apac-sin":   "ap-southeast-1",
+	"apac-syd":   "ap-southeast-2",
+	"apac-tokyo": "ap-northeast-1",
+}
+
+// awsSpotFeed is the JSONP payload of the AWS spot price feed
+type awsSpotFeed struct {
+	Config struct {
+		Regions []struct {
+			Region        string `json:"region"`
+			InstanceTypes []struct {
+				Sizes []struct {
+					Size         string `json:"size"`
+					ValueColumns []struct {
+						Name   string            `json:"name"`
+						Prices map[string]string `json:"prices"`
+					} `json:"valueColumns"`
+				} `json:"sizes"`
+			} `json:"instanceTypes"`
+		} `json:"regions"`
+	} `json:"config"`
+}
+
+// awsSpotFallback builds deals for regions from the AWS spot price feed.
+// The feed carries Linux prices only, so vCPU and memory specs are borrowed
+// from the same instance types in regions ec2.shop did return, and the
+// savings-rate filter cannot be applied.
+func awsSpotFallback(regions []string, fetched SpotData) (map[string][]Instance, error) {
+	resp, err := http.Get(awsSpotFeedURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUpstreamUnavailable, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: spot feed: unexpected status %s", ErrUpstreamUnavailable, resp.Status)
+	}
+
+	body, err := readLimited(resp.Body, maxResponseBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	// Strip the JSONP callback wrapper
+	start, end := strings.IndexByte(string(body), '('), strings.LastIndexByte(string(body), ')')
+	if start < 0 || end <= start {
+		return nil, fmt.Errorf("%w: unexpected spot feed format", ErrDecode)
+	}
+	var feed awsSpotFeed
+	if err := json.Unmarshal(body[start+1:end], &feed); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDecode, err)
+	}
+
+	// Regions are visited in any order, so normalize the borrowed specs to
+	// make them the same whichever region lends them
+	specs := make(map[string]Instance)
+	for _, instances := range fetched.Regions {
+		for _, instance := range instances {
+			specs[instance.InstanceType] = canonicalInstance(instance)
+		}
+	}
+
+	wanted := make(map[string]bool)
+	for _, region := range regions {
+		wanted[region] = true
+	}
+
+	deals := make(map[string][]Instance)
+	for _, feedRegion := range feed.Config.Regions {
+		region := feedRegion.Region
+		if code, ok := awsSpotFeedRegions[region]; ok {
+			region = code
+		}
+		if !wanted[region] {
+			continue
+		}
+
+		var instances []Instance
+		for _, instanceType := range feedRegion.InstanceTypes {
+			for _, size := range instanceType.Sizes {
+				spec, ok := specs[size.Size]
+				if !ok {
+					continue
+				}
+				for _, column := range size.ValueColumns {
+					if column.Name != "linux" {
+						continue
+					}
+					// Unavailable sizes are priced "N/A*"
+					if _, err := strconv.ParseFloat(column.Prices["USD"], 64); err != nil {
+						continue
+					}
+					instances = append(instances, Instance{
+						InstanceType: size.Size,
+						VCPUS:        spec.VCPUS,
+						Memory:       spec.Memory,
+						SpotPrice:    column.Prices["USD"],
+					})
+				}
+			}
+		}
+
+		// Rank like the ec2.shop deals
+		applyInterruptionRates(region, instances)
+		applyCategories("aws", instances)
+		instances = applyDeprecations(instances)
+		applyCredits(instances)
+		instances = policy.Filter(region, instances)
+		rankInstances(instances, regionStrategy)
+		if err := validateInstances(instances); err != nil {
+			return nil, fmt.Errorf("region %s: %w", region, err)
+		}
+		if len(instances) > 0 {
+			deals[region] = instances
+		}
+	}
+
+	return deals, nil
+}
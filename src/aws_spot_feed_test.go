@@ -0,0 +1,21 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAWSSpotFallbackRejectsErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "callback(maintenance)", http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(server.Close)
+	setVar(t, &awsSpotFeedURL, server.URL)
+
+	_, err := awsSpotFallback([]string{"eu-west-1"}, SpotData{})
+	if !errors.Is(err, ErrUpstreamUnavailable) {
+		t.Errorf("awsSpotFallback error = %v, want ErrUpstreamUnavailable", err)
+	}
+}
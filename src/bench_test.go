@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// syntheticInstances lists n instances of distinct types priced by seed
+func syntheticInstances(n int, seed float64) []Instance {
+	instances := make([]Instance, n)
+	for i := range instances {
+		price := 0.05 + float64(i%97)/100 + seed
+		instances[i] = Instance{
+			InstanceType:     fmt.Sprintf("c%dg.%dxlarge", 5+i%3, 1+i),
+			VCPUS:            4 * (1 + i%8),
+			Memory:           fmt.Sprintf("%d GiB", 8*(1+i%8)),
+			SpotSavingRate:   "60%",
+			SpotPrice:        fmt.Sprintf("%.4f", price),
+			OnDemandPrice:    fmt.Sprintf("%.4f", price*2.5),
+			InterruptionRate: interruptionLevels[i%len(interruptionLevels)],
+		}
+	}
+	return instances
+}
+
+// syntheticSpotData is a dataset of regions regions listing instances
+// instances each; 30 regions of 200 are about the size of the published one
+func syntheticSpotData(regions, instances int, seed float64) SpotData {
+	data := SpotData{
+		LastUpdated:    testNow.Format(time.RFC3339),
+		Regions:        make(map[string][]Instance, regions),
+		Sources:        make(map[string]string, regions),
+		RegionsUpdated: make(map[string]string, regions),
+	}
+	for r := 0; r < regions; r++ {
+		region := fmt.Sprintf("region-%d", r)
+		data.Regions[region] = syntheticInstances(instances, seed)
+		data.Sources[region] = sourceEC2Shop
+		data.RegionsUpdated[region] = data.LastUpdated
+	}
+	return data
+}
+
+// shopResponse renders instances as an ec2.shop response
+func shopResponse(instances []Instance) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(`{"Prices":[`)
+	for i, instance := range instances {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		fmt.Fprintf(&buf, `{"InstanceType":%q,"VCPUS":%d,"Memory":%q,"SpotSavingRate":%q,"SpotPrice":%q,"Cost":%s}`,
+			instance.InstanceType, instance.VCPUS, instance.Memory, instance.SpotSavingRate, instance.SpotPrice, instance.OnDemandPrice)
+	}
+	buf.WriteString(`]}`)
+	return buf.Bytes()
+}
+
+func BenchmarkDecodePrices(b *testing.B) {
+	response := shopResponse(syntheticInstances(1000, 0))
+	b.SetBytes(int64(len(response)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := decodePrices(bytes.NewReader(response), func(Instance) bool { return true }); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkFetchRegionPrices measures a region's fetch and parse, over HTTP
+// from a local server
+func BenchmarkFetchRegionPrices(b *testing.B) {
+	response := shopResponse(syntheticInstances(1000, 0))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(response)
+	}))
+	defer server.Close()
+	setVar(b, &ec2ShopURL, server.URL)
+
+	b.SetBytes(int64(len(response)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := fetchRegionPrices("eu-west-1", "", func(Instance) bool { return true }); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMergeSpotData(b *testing.B) {
+	existing := syntheticSpotData(30, 200, 0)
+	fresh := syntheticSpotData(30, 200, 0.01)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		mergeSpotData(existing, fresh)
+	}
+}
+
+func BenchmarkWriteSpotData(b *testing.B) {
+	data := syntheticSpotData(30, 200, 0)
+	for _, bench := range []struct {
+		name  string
+		write func(io.Writer, SpotData) error
+	}{
+		{"indented", writeSpotData},
+		{"minified", writeMinifiedSpotData},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if err := bench.write(io.Discard, data); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SavingsBucket groups a region's instances by savings rate. Min is
+// inclusive, Max exclusive; a nil bound leaves that side open.
+type SavingsBucket struct {
+	Label     string   `json:"label"`
+	Min       *int     `json:"min"`
+	Max       *int     `json:"max"`
+	Instances []string `json:"instances"`
+}
+
+// savingsBuckets tiers every region's instances by the configured
+// ascending boundaries, e.g. [50, 60, 70] yields 50–60%, 60–70% and 70%+.
+// Instances below the first boundary (relaxed ones) get their own tier.
+// Empty tiers are omitted.
+func savingsBuckets(regions map[string][]Instance, boundaries []int) map[string][]SavingsBucket {
+	bounds := append([]int(nil), boundaries...)
+	sort.Ints(bounds)
+	if len(bounds) == 0 {
+		return nil
+	}
+
+	result := make(map[string][]SavingsBucket, len(regions))
+	for region, instances := range regions {
+		// Index 0 is the tier below the first boundary
+		tiers := make([][]string, len(bounds)+1)
+		for _, instance := range instances {
+			rate, err := strconv.Atoi(strings.TrimSuffix(instance.SpotSavingRate, "%"))
+			if err != nil {
+				continue
+			}
+			tier := sort.Search(len(bounds), func(i int) bool { return bounds[i] > rate })
+			tiers[tier] = append(tiers[tier], instance.InstanceType)
+		}
+
+		var buckets []SavingsBucket
+		for i, types := range tiers {
+			if len(types) == 0 {
+				continue
+			}
+			bucket := SavingsBucket{Instances: types}
+			switch {
+			case i == 0:
+				bucket.Label = fmt.Sprintf("below %d%%", bounds[0])
+				bucket.Max = &bounds[0]
+			case i == len(bounds):
+				bucket.Label = fmt.Sprintf("%d%%+", bounds[i-1])
+				bucket.Min = &bounds[i-1]
+			default:
+				bucket.Label = fmt.Sprintf("%d–%d%%", bounds[i-1], bounds[i])
+				bucket.Min, bucket.Max = &bounds[i-1], &bounds[i]
+			}
+			buckets = append(buckets, bucket)
+		}
+		result[region] = buckets
+	}
+	return result
+}
@@ -0,0 +1,74 @@
+package main
+
+import "math"
+
+// hoursPerMonth is the average number of hours AWS bills a month for
+const hoursPerMonth = 730
+
+// List prices per GB-month of gp3 storage and per GB of internet egress
+// (first 10 TB tier), keyed by region with a fallback under "default". The
+// config's bundle prices override or extend them.
+var (
+	defaultGP3Prices = map[string]float64{
+		"default": 0.08, "us-east-1": 0.08, "us-east-2": 0.08, "us-west-2": 0.08,
+		"eu-west-1": 0.088, "eu-central-1": 0.0952, "ap-south-1": 0.0912,
+		"ap-northeast-1": 0.096, "ap-southeast-1": 0.096, "sa-east-1": 0.152,
+	}
+	defaultEgressPrices = map[string]float64{
+		"default": 0.09, "ap-south-1": 0.1093, "ap-northeast-1": 0.114,
+		"ap-southeast-1": 0.12, "sa-east-1": 0.15,
+	}
+)
+
+// BundleConfig describes the resources a workload attaches to its instance
+type BundleConfig struct {
+	StorageGB float64 `json:"storage_gb"` // gp3 volume size
+	EgressGB  float64 `json:"egress_gb"`  // internet egress per month
+	// GP3Prices and EgressPrices override the built-in per-region prices
+	GP3Prices    map[string]float64 `json:"gp3_prices"`
+	EgressPrices map[string]float64 `json:"egress_prices"`
+}
+
+// BundleCost is the monthly cost of a deal with its attached resources
+type BundleCost struct {
+	Instance float64 `json:"instance"`
+	Storage  float64 `json:"storage"`
+	Egress   float64 `json:"egress"`
+	Total    float64 `json:"total"`
+}
+
+// regionPrice looks up region in the override prices, then the built-in
+// ones, falling back to their defaults
+func regionPrice(region string, overrides, defaults map[string]float64) float64 {
+	if price, ok := overrides[region]; ok {
+		return price
+	}
+	if price, ok := defaults[region]; ok {
+		return price
+	}
+	if price, ok := overrides["default"]; ok {
+		return price
+	}
+	return defaults["default"]
+}
+
+// bundleCost prices a month of the instance with the configured storage
+// and egress in region; nil when no bundle is configured
+func bundleCost(region string, hourlyPrice float64) *BundleCost {
+	bundle := config.Bundle
+	if bundle == nil || hourlyPrice <= 0 {
+		return nil
+	}
+	cost := BundleCost{
+		Instance: roundCents(hourlyPrice * hoursPerMonth),
+		Storage:  roundCents(bundle.StorageGB * regionPrice(region, bundle.GP3Prices, defaultGP3Prices)),
+		Egress:   roundCents(bundle.EgressGB * regionPrice(region, bundle.EgressPrices, defaultEgressPrices)),
+	}
+	cost.Total = roundCents(cost.Instance + cost.Storage + cost.Egress)
+	return &cost
+}
+
+// roundCents rounds a dollar amount to whole cents
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
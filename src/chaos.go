@@ -0,0 +1,157 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// chaosSpec enables failure injection on upstream requests for integration
+// tests. It is hidden from usage and completion output.
+var chaosSpec = flag.String("chaos", "", "")
+
+// hiddenFlags are left out of usage and completion output
+var hiddenFlags = map[string]bool{"chaos": true}
+
+func init() {
+	flag.Usage = func() {
+		visible := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+		flag.VisitAll(func(f *flag.Flag) {
+			if !hiddenFlags[f.Name] {
+				visible.Var(f.Value, f.Name, f.Usage)
+			}
+		})
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage of %s:\n", os.Args[0])
+		visible.SetOutput(flag.CommandLine.Output())
+		visible.PrintDefaults()
+	}
+}
+
+// chaosConfig is parsed from a spec such as "failure=0.2,slow=0.1,truncate=0.1,delay=5s,seed=42",
+// where failure, slow and truncate are per-request probabilities
+type chaosConfig struct {
+	Failure  float64
+	Slow     float64
+	Truncate float64
+	Delay    time.Duration
+	Seed     int64
+}
+
+// parseChaosSpec parses a --chaos value
+func parseChaosSpec(spec string) (chaosConfig, error) {
+	c := chaosConfig{Delay: 5 * time.Second, Seed: randomSeed()}
+	for _, field := range strings.Split(spec, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(field), "=")
+		if !ok {
+			return c, fmt.Errorf("invalid chaos setting %q, expected key=value", field)
+		}
+		var err error
+		switch key {
+		case "failure":
+			c.Failure, err = parseProbability(value)
+		case "slow":
+			c.Slow, err = parseProbability(value)
+		case "truncate":
+			c.Truncate, err = parseProbability(value)
+		case "delay":
+			c.Delay, err = time.ParseDuration(value)
+		case "seed":
+			c.Seed, err = strconv.ParseInt(value, 10, 64)
+		default:
+			err = errors.New("unknown setting")
+		}
+		if err != nil {
+			return c, fmt.Errorf("chaos setting %q: %v", field, err)
+		}
+	}
+	return c, nil
+}
+
+// parseProbability parses a probability between 0 and 1
+func parseProbability(value string) (float64, error) {
+	p, err := strconv.ParseFloat(value, 64)
+	if err != nil || p < 0 || p > 1 {
+		return 0, errors.New("expected a probability between 0 and 1")
+	}
+	return p, nil
+}
+
+// enableChaos routes every outgoing request through a chaosTransport
+func enableChaos(spec string) error {
+	c, err := parseChaosSpec(spec)
+	if err != nil {
+		return err
+	}
+	log.Printf("Chaos mode enabled: failure=%.2f slow=%.2f truncate=%.2f delay=%s seed=%d", c.Failure, c.Slow, c.Truncate, c.Delay, c.Seed)
+	http.DefaultTransport = &chaosTransport{next: http.DefaultTransport, config: c, rands: make(map[string]*rand.Rand)}
+	return nil
+}
+
+// chaosTransport injects upstream failures, slow responses and truncated bodies
+type chaosTransport struct {
+	next   http.RoundTripper
+	config chaosConfig
+
+	mu    sync.Mutex
+	rands map[string]*rand.Rand // per URL, such as a region's ec2.shop prices
+}
+
+// roll draws the outcomes for one request, safe for concurrent fetches.
+// Each URL draws from its own source, seeded from the seed and the URL, so a
+// seed picks the same failures whatever order concurrent regions run in.
+func (t *chaosTransport) roll(url string) (fail, slow, truncate bool, cut float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	r, ok := t.rands[url]
+	if !ok {
+		h := fnv.New64a()
+		h.Write([]byte(url))
+		r = rand.New(rand.NewSource(t.config.Seed ^ int64(h.Sum64())))
+		t.rands[url] = r
+	}
+	return r.Float64() < t.config.Failure, r.Float64() < t.config.Slow, r.Float64() < t.config.Truncate, r.Float64()
+}
+
+func (t *chaosTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	fail, slow, truncate, cut := t.roll(req.URL.String())
+	if fail {
+		log.Printf("Chaos: failing request to %s", req.URL.Host)
+		return nil, errors.New("chaos: injected upstream failure")
+	}
+	if slow {
+		log.Printf("Chaos: delaying request to %s by %s", req.URL.Host, t.config.Delay)
+		select {
+		case <-time.After(t.config.Delay):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || !truncate {
+		return resp, err
+	}
+
+	// Cut the body at a random point of its first 64 KiB
+	limit := int64(cut * (64 << 10))
+	log.Printf("Chaos: truncating response from %s after %d bytes", req.URL.Host, limit)
+	resp.Body = truncatedBody{io.LimitReader(resp.Body, limit), resp.Body}
+	resp.ContentLength = -1
+	return resp, nil
+}
+
+// truncatedBody reads a prefix of a response body but closes the whole of it
+type truncatedBody struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// TestChaosRollIndependentOfOrder checks a seed injects the same failures
+// whatever order concurrent region fetches reach the transport in
+func TestChaosRollIndependentOfOrder(t *testing.T) {
+	var urls []string
+	for i := 0; i < 30; i++ {
+		for attempt := 0; attempt < 3; attempt++ {
+			urls = append(urls, fmt.Sprintf("https://ec2.shop?region=region-%d", i))
+		}
+	}
+
+	rolls := func(order []int) map[string][]bool {
+		transport := &chaosTransport{config: chaosConfig{Failure: 0.3, Slow: 0.3, Truncate: 0.3, Seed: 42}, rands: make(map[string]*rand.Rand)}
+		got := make(map[string][]bool)
+		for _, i := range order {
+			fail, slow, truncate, _ := transport.roll(urls[i])
+			got[urls[i]] = append(got[urls[i]], fail, slow, truncate)
+		}
+		return got
+	}
+
+	inOrder := make([]int, len(urls))
+	for i := range inOrder {
+		inOrder[i] = i
+	}
+	want := rolls(inOrder)
+	for seed := int64(1); seed <= 5; seed++ {
+		shuffled := rand.New(rand.NewSource(seed)).Perm(len(urls))
+		got := rolls(shuffled)
+		for url, outcomes := range want {
+			if fmt.Sprint(got[url]) != fmt.Sprint(outcomes) {
+				t.Fatalf("order %d: %s rolled %v, want %v", seed, url, got[url], outcomes)
+			}
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+)
+
+var (
+	checkFlags  = flag.NewFlagSet("check", flag.ExitOnError)
+	checkBudget = checkFlags.Float64("budget-per-vcpu", 0, "maximum acceptable spot price per vCPU-hour in USD")
+	checkRegion = checkFlags.String("region", "", "AWS region to check (e.g. eu-west-1)")
+)
+
+// runCheck implements the check command: it fetches live deals for a region
+// and exits non-zero when no instance fits within the per-vCPU budget
+func runCheck(args []string) {
+	checkFlags.Parse(args)
+
+	if *checkBudget <= 0 || *checkRegion == "" {
+		fmt.Fprintln(os.Stderr, "usage: check --budget-per-vcpu <usd> --region <region>")
+		checkFlags.PrintDefaults()
+		os.Exit(2)
+	}
+
+	deals, err := getSpotDeals(*checkRegion)
+	if err != nil {
+		log.Fatalf("Error getting spot deals for region %s: %v", *checkRegion, err)
+	}
+
+	// Keep the instances whose price per vCPU fits within the budget
+	var matches []Instance
+	for _, instance := range deals {
+		price, err := strconv.ParseFloat(instance.SpotPrice, 64)
+		if err != nil || instance.VCPUS == 0 {
+			continue
+		}
+		if price/float64(instance.VCPUS) <= *checkBudget {
+			matches = append(matches, instance)
+		}
+	}
+
+	if len(matches) == 0 {
+		log.Printf("No instance in %s meets the budget of $%.6f per vCPU", *checkRegion, *checkBudget)
+		os.Exit(1)
+	}
+
+	printInstanceTable(os.Stdout, matches, nil)
+}
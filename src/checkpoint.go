@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+var (
+	checkpointFile   = flag.String("checkpoint", "", "record each region's fetch result in this file so an interrupted refresh can resume, e.g. for local runs against a flaky network")
+	checkpointMaxAge = flag.Duration("checkpoint-max-age", time.Hour, "age beyond which checkpointed regions are fetched again")
+)
+
+// checkpoint holds the regions fetched by an interrupted refresh; nil when
+// checkpointing is disabled
+var checkpoint *regionCheckpoint
+
+// checkpointEntry is one region's fetch result
+type checkpointEntry struct {
+	Region    string     `json:"region"`
+	Instances []Instance `json:"instances"`
+	Fetched   time.Time  `json:"fetched"`
+}
+
+// regionCheckpoint appends every fetched region to a JSON Lines file, so a
+// run that is killed part way only re-fetches the regions it had not reached
+type regionCheckpoint struct {
+	filename string
+
+	mu      sync.Mutex
+	file    *os.File
+	fetched map[string]checkpointEntry
+}
+
+// openCheckpoint loads the regions of a previous run fetched within maxAge.
+// A truncated last line from an interrupted write is dropped by rewriting
+// the file with only the entries kept.
+func openCheckpoint(filename string, maxAge time.Duration) (*regionCheckpoint, error) {
+	c := &regionCheckpoint{filename: filename, fetched: make(map[string]checkpointEntry)}
+
+	var kept []checkpointEntry
+	if file, err := os.Open(filename); err == nil {
+		dec := json.NewDecoder(bufio.NewReader(file))
+		for {
+			var entry checkpointEntry
+			if err := dec.Decode(&entry); err != nil {
+				break
+			}
+			if clock().Sub(entry.Fetched) <= maxAge {
+				c.fetched[entry.Region] = entry
+				kept = append(kept, entry)
+			}
+		}
+		file.Close()
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	if len(c.fetched) > 0 {
+		log.Printf("Resuming from checkpoint %s with %d regions already fetched", filename, len(c.fetched))
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".*")
+	if err != nil {
+		return nil, err
+	}
+	enc := json.NewEncoder(tmp)
+	for _, entry := range kept {
+		if err := enc.Encode(entry); err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+			return nil, err
+		}
+	}
+	if err := os.Rename(tmp.Name(), filename); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+	c.file = tmp
+	return c, nil
+}
+
+// Lookup returns the checkpointed instances of region and when they were
+// fetched, if any
+func (c *regionCheckpoint) Lookup(region string) ([]Instance, time.Time, bool) {
+	if c == nil {
+		return nil, time.Time{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.fetched[region]
+	return entry.Instances, entry.Fetched, ok
+}
+
+// Save records region's instances. A failed write only costs a re-fetch on
+// resume, so it is logged rather than failing the run.
+func (c *regionCheckpoint) Save(region string, instances []Instance) {
+	if c == nil {
+		return
+	}
+	line, err := json.Marshal(checkpointEntry{Region: region, Instances: instances, Fetched: clock().UTC()})
+	if err != nil {
+		log.Printf("Error checkpointing region %s: %v", region, err)
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := c.file.Write(append(line, '\n')); err != nil {
+		log.Printf("Error checkpointing region %s: %v", region, err)
+	}
+}
+
+// Remove deletes the checkpoint once the refresh no longer needs it
+func (c *regionCheckpoint) Remove() {
+	if c == nil {
+		return
+	}
+	c.file.Close()
+	if err := os.Remove(c.filename); err != nil && !os.IsNotExist(err) {
+		log.Printf("Error removing checkpoint: %v", err)
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFetchSpotDataDatesCheckpointedRegions(t *testing.T) {
+	mockUpstreams(t)
+	setFlag(t, "locations-cache", filepath.Join(t.TempDir(), "locations.json"))
+
+	filename := filepath.Join(t.TempDir(), "checkpoint.jsonl")
+	c, err := openCheckpoint(filename, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fetched := clock().Add(-30 * time.Minute)
+	now := clock
+	clock = func() time.Time { return fetched }
+	c.Save("eu-west-1", []Instance{{InstanceType: "m5.large", VCPUS: 2, Memory: "8 GiB", SpotPrice: "0.0400"}})
+	clock = now
+
+	if c, err = openCheckpoint(filename, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	old := checkpoint
+	checkpoint = c
+	t.Cleanup(func() { checkpoint = old })
+
+	data, err := fetchSpotData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := data.RegionsUpdated["eu-west-1"], fetched.UTC().Format(time.RFC3339); got != want {
+		t.Errorf("checkpointed region updated %s, want %s", got, want)
+	}
+	if got := data.RegionsUpdated["us-east-1"]; got != data.LastUpdated {
+		t.Errorf("fetched region updated %s, want %s", got, data.LastUpdated)
+	}
+}
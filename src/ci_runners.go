@@ -0,0 +1,178 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"unicode"
+)
+
+var (
+	ciRunnersFlags  = flag.NewFlagSet("ci-runners", flag.ExitOnError)
+	ciRunnersRegion = ciRunnersFlags.String("region", "", "AWS region to run CI runners in (e.g. eu-west-1)")
+	ciRunnersLimit  = ciRunnersFlags.Int("limit", 10, "number of instance types to list")
+	ciRunnersArch   = ciRunnersFlags.String("arch", "", "only consider this architecture: arm64 or x86_64")
+	ciRunnersFormat = ciRunnersFlags.String("format", "text", "output format: text, or arc for gha-runner-scale-set Helm values")
+)
+
+// Size range of a CI runner: enough cores for parallel builds, small enough
+// that a runner does not idle most of a large instance
+const (
+	ciRunnerMinVCPUs = 4
+	ciRunnerMaxVCPUs = 16
+)
+
+// Discounts applied to the price per vCPU when ranking runners, standing in
+// for the shorter builds of fast cores and local NVMe scratch space
+const (
+	ciHighClockDiscount = 0.15
+	ciNVMeDiscount      = 0.10
+)
+
+// CIRunner is an instance type ranked for CI runners
+type CIRunner struct {
+	Instance  Instance
+	HighClock bool
+	NVMe      bool
+	// Score is the price per vCPU after discounts; lower ranks first
+	Score float64
+}
+
+// familyAttributes splits an EC2 family into its series letters and the
+// attribute letters after the generation (m5zn → "m", "zn")
+func familyAttributes(instanceType string) (string, string) {
+	family, _, _ := strings.Cut(strings.ToLower(instanceType), ".")
+	series := letterPrefix(family)
+	i := series
+	for i < len(family) && unicode.IsDigit(rune(family[i])) {
+		i++
+	}
+	return family[:series], family[i:]
+}
+
+// isHighClock reports whether a type belongs to a compute-optimized or
+// high-frequency family (c7i, z1d, m5zn)
+func isHighClock(instanceType string) bool {
+	series, attributes := familyAttributes(instanceType)
+	return series == "c" || series == "z" || strings.Contains(attributes, "z")
+}
+
+// hasLocalNVMe reports whether a type comes with NVMe instance storage:
+// the "d" variants (c6gd, m5ad) and the storage-optimized i families
+func hasLocalNVMe(instanceType string) bool {
+	series, attributes := familyAttributes(instanceType)
+	return strings.Contains(attributes, "d") || series == "i" || series == "im" || series == "is"
+}
+
+// rankCIRunners keeps the runner-sized deals and orders them by their
+// discounted price per vCPU
+func rankCIRunners(deals []Instance) []CIRunner {
+	var runners []CIRunner
+	for _, instance := range deals {
+		price := parsePrice(instance.SpotPrice)
+		if price <= 0 || instance.VCPUS < ciRunnerMinVCPUs || instance.VCPUS > ciRunnerMaxVCPUs {
+			continue
+		}
+		runner := CIRunner{
+			Instance:  instance,
+			HighClock: isHighClock(instance.InstanceType),
+			NVMe:      hasLocalNVMe(instance.InstanceType),
+		}
+		discount := 1.0
+		if runner.HighClock {
+			discount -= ciHighClockDiscount
+		}
+		if runner.NVMe {
+			discount -= ciNVMeDiscount
+		}
+		runner.Score = price / float64(instance.VCPUS) * discount
+		runners = append(runners, runner)
+	}
+	sort.SliceStable(runners, func(i, j int) bool { return runners[i].Score < runners[j].Score })
+	return runners
+}
+
+// runCIRunners implements the ci-runners command: it ranks the current
+// deals of a region for self-hosted CI runners
+func runCIRunners(args []string) {
+	ciRunnersFlags.Parse(args)
+
+	validArch := *ciRunnersArch == "" || *ciRunnersArch == "arm64" || *ciRunnersArch == "x86_64"
+	if *ciRunnersRegion == "" || *ciRunnersLimit <= 0 || !validArch {
+		fmt.Fprintln(os.Stderr, "usage: ci-runners --region <region> [--limit 10] [--arch arm64|x86_64] [--format text|arc]")
+		ciRunnersFlags.PrintDefaults()
+		os.Exit(2)
+	}
+
+	deals, err := getSpotDeals(*ciRunnersRegion)
+	if err != nil {
+		log.Fatalf("Error getting spot deals for region %s: %v", *ciRunnersRegion, err)
+	}
+
+	var candidates []Instance
+	for _, instance := range deals {
+		if *ciRunnersArch == "" || instanceArchitecture(instance.InstanceType) == *ciRunnersArch {
+			candidates = append(candidates, instance)
+		}
+	}
+	runners := rankCIRunners(candidates)
+	if len(runners) == 0 {
+		log.Fatalf("No %d-%d vCPU instance among the current deals in %s", ciRunnerMinVCPUs, ciRunnerMaxVCPUs, *ciRunnersRegion)
+	}
+	if len(runners) > *ciRunnersLimit {
+		runners = runners[:*ciRunnersLimit]
+	}
+
+	switch *ciRunnersFormat {
+	case "text":
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "INSTANCE TYPE\tVCPUS\tMEMORY\tSPOT PRICE\tPRICE PER VCPU\tHIGH CLOCK\tNVME")
+		for _, runner := range runners {
+			instance := runner.Instance
+			fmt.Fprintf(w, "%s\t%d\t%s\t$%s\t$%.6f\t%s\t%s\n", instance.InstanceType, instance.VCPUS, instance.Memory, instance.SpotPrice,
+				parsePrice(instance.SpotPrice)/float64(instance.VCPUS), yesNo(runner.HighClock), yesNo(runner.NVMe))
+		}
+		w.Flush()
+	case "arc":
+		fmt.Print(arcRunnerValues(runners))
+	default:
+		log.Fatalf("Unsupported format %q", *ciRunnersFormat)
+	}
+}
+
+// yesNo renders a boolean table cell
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+// arcRunnerValues renders Helm values for the gha-runner-scale-set chart of
+// Actions Runner Controller, scheduling runner pods onto the ranked types
+func arcRunnerValues(runners []CIRunner) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# gha-runner-scale-set values: runners on the %d cheapest CI instance types in %s\n", len(runners), *ciRunnersRegion)
+	b.WriteString("template:\n")
+	b.WriteString("  spec:\n")
+	b.WriteString("    affinity:\n")
+	b.WriteString("      nodeAffinity:\n")
+	b.WriteString("        requiredDuringSchedulingIgnoredDuringExecution:\n")
+	b.WriteString("          nodeSelectorTerms:\n")
+	b.WriteString("            - matchExpressions:\n")
+	b.WriteString("                - key: node.kubernetes.io/instance-type\n")
+	b.WriteString("                  operator: In\n")
+	b.WriteString("                  values:\n")
+	for _, runner := range runners {
+		fmt.Fprintf(&b, "                    - %s # $%s/hour\n", runner.Instance.InstanceType, runner.Instance.SpotPrice)
+	}
+	b.WriteString("    containers:\n")
+	b.WriteString("      - name: runner\n")
+	b.WriteString("        image: ghcr.io/actions/actions-runner:latest\n")
+	b.WriteString("        command: [\"/home/runner/run.sh\"]\n")
+	return b.String()
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+var (
+	fixedNow     = flag.String("now", "", "run as if at this RFC 3339 time, for reproducible output")
+	reproducible = flag.Bool("reproducible", false, "run as if at the time in SOURCE_DATE_EPOCH unless --now is given, following the reproducible builds convention")
+	runSeed      = flag.Int64("seed", 0, "seed the run's randomness, such as chaos mode, with this value instead of the current time")
+)
+
+// clock returns the current time. Timestamps that end up in the published
+// data or its history read it instead of time.Now, so a run can be pinned.
+var clock = time.Now
+
+// fixClock pins clock to spec, an RFC 3339 time, or when empty and the run
+// is reproducible to the Unix time in SOURCE_DATE_EPOCH. The variable alone
+// does not pin the clock, as build environments may set it for other tools.
+func fixClock(spec string, reproducible bool) error {
+	var at time.Time
+	switch epoch := os.Getenv("SOURCE_DATE_EPOCH"); {
+	case spec != "":
+		var err error
+		if at, err = time.Parse(time.RFC3339, spec); err != nil {
+			return fmt.Errorf("invalid --now %q, expected an RFC 3339 time", spec)
+		}
+	case reproducible && epoch == "":
+		return errors.New("--reproducible needs --now or SOURCE_DATE_EPOCH")
+	case reproducible:
+		seconds, err := strconv.ParseInt(epoch, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid SOURCE_DATE_EPOCH %q, expected Unix seconds", epoch)
+		}
+		at = time.Unix(seconds, 0)
+	default:
+		return nil
+	}
+	at = at.UTC()
+	clock = func() time.Time { return at }
+	return nil
+}
+
+// randomSeed returns the --seed value, or a time-based seed when unset
+func randomSeed() int64 {
+	if *runSeed != 0 {
+		return *runSeed
+	}
+	return time.Now().UnixNano()
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFixClock(t *testing.T) {
+	pinned := clock
+	t.Cleanup(func() { clock = pinned })
+	t.Setenv("SOURCE_DATE_EPOCH", "1700000000")
+	epoch := time.Unix(1700000000, 0).UTC()
+
+	tests := []struct {
+		name         string
+		now          string
+		reproducible bool
+		want         time.Time // zero when the clock stays unpinned
+		wantErr      bool
+	}{
+		{name: "SOURCE_DATE_EPOCH alone is ignored"},
+		{name: "reproducible", reproducible: true, want: epoch},
+		{name: "--now wins", now: "2024-01-01T00:00:00Z", reproducible: true, want: testNow},
+		{name: "invalid --now", now: "yesterday", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clock = time.Now
+			err := fixClock(test.now, test.reproducible)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("fixClock: %v", err)
+			}
+			if got := clock(); !test.want.IsZero() && !got.Equal(test.want) {
+				t.Errorf("clock() = %s, want %s", got, test.want)
+			} else if test.want.IsZero() && got.Equal(epoch) {
+				t.Errorf("clock() pinned to SOURCE_DATE_EPOCH without --reproducible")
+			}
+		})
+	}
+
+	t.Setenv("SOURCE_DATE_EPOCH", "")
+	if err := fixClock("", true); err == nil {
+		t.Error("--reproducible without a time was accepted")
+	}
+}
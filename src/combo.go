@@ -0,0 +1,196 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+var (
+	comboFlags           = flag.NewFlagSet("combo", flag.ExitOnError)
+	comboVCPUs           = comboFlags.Int("vcpus", 0, "total number of vCPUs the fleet needs")
+	comboRegion          = comboFlags.String("region", "", "AWS region to plan for (e.g. eu-west-1)")
+	comboTypes           = comboFlags.Int("types", 4, "number of instance families to spread the fleet across (3-5)")
+	comboMaxInterruption = comboFlags.String("max-interruption", "10-15%", "highest Spot Instance Advisor interruption range to accept; empty accepts unrated types")
+	comboArch            = comboFlags.String("arch", "", "only recommend instance types of this architecture: arm64 or x86_64")
+)
+
+// Bounds on how many capacity pools a combo spreads across
+const (
+	minComboTypes = 3
+	maxComboTypes = 5
+)
+
+// ComboPick is one instance type of a recommended combo and how many of it to run
+type ComboPick struct {
+	Instance   Instance
+	Count      int
+	VCPUS      int
+	HourlyCost float64
+}
+
+// Combo is a diversified mix of instance types covering a vCPU requirement
+type Combo struct {
+	Picks      []ComboPick
+	VCPUS      int
+	HourlyCost float64
+	// ExpectedInterruption is the vCPU-weighted midpoint of the picks' interruption ranges, in percent
+	ExpectedInterruption float64
+	// LargestShare is the fraction of the fleet a single interrupted pool would take down
+	LargestShare float64
+}
+
+// recommendCombo spreads vcpus about evenly across the cheapest types per vCPU,
+// taking at most one type per family since sizes of a family tend to be
+// reclaimed together. Types above maxInterruption are skipped, as are
+// unrated ones unless maxInterruption is empty.
+func recommendCombo(deals []Instance, vcpus, types int, maxInterruption string) (Combo, error) {
+	maxLevel := len(interruptionLevels)
+	if maxInterruption != "" {
+		maxLevel = interruptionLevel(maxInterruption)
+		if maxLevel == len(interruptionLevels) {
+			return Combo{}, fmt.Errorf("unknown interruption range %q, expected one of %s", maxInterruption, strings.Join(interruptionLevels, ", "))
+		}
+	}
+
+	// Keep the cheapest qualifying type of each family
+	cheapest := map[string]Instance{}
+	perVCPU := func(instance Instance) float64 {
+		price, _ := strconv.ParseFloat(instance.SpotPrice, 64)
+		return price / float64(instance.VCPUS)
+	}
+	for _, instance := range deals {
+		if _, err := strconv.ParseFloat(instance.SpotPrice, 64); err != nil || instance.VCPUS == 0 {
+			continue
+		}
+		if interruptionLevel(instance.InterruptionRate) > maxLevel {
+			continue
+		}
+		family, _, _ := strings.Cut(instance.InstanceType, ".")
+		if current, ok := cheapest[family]; !ok || perVCPU(instance) < perVCPU(current) {
+			cheapest[family] = instance
+		}
+	}
+
+	var candidates []Instance
+	for _, instance := range cheapest {
+		candidates = append(candidates, instance)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if perVCPU(candidates[i]) != perVCPU(candidates[j]) {
+			return perVCPU(candidates[i]) < perVCPU(candidates[j])
+		}
+		return candidates[i].InstanceType < candidates[j].InstanceType
+	})
+	if len(candidates) < types {
+		return Combo{}, fmt.Errorf("only %d instance families qualify, need %d", len(candidates), types)
+	}
+
+	// Give each type an equal share of what is still needed, rounded up to
+	// whole instances, so rounding early does not inflate every later share
+	var combo Combo
+	for i, instance := range candidates[:types] {
+		price, _ := strconv.ParseFloat(instance.SpotPrice, 64)
+		share := math.Max(float64(vcpus-combo.VCPUS), 1) / float64(types-i)
+		count := int(math.Ceil(share / float64(instance.VCPUS)))
+		pick := ComboPick{
+			Instance:   instance,
+			Count:      count,
+			VCPUS:      count * instance.VCPUS,
+			HourlyCost: float64(count) * price,
+		}
+		combo.Picks = append(combo.Picks, pick)
+		combo.VCPUS += pick.VCPUS
+		combo.HourlyCost += pick.HourlyCost
+	}
+
+	for _, pick := range combo.Picks {
+		weight := float64(pick.VCPUS) / float64(combo.VCPUS)
+		if level := interruptionLevel(pick.Instance.InterruptionRate); level < len(interruptionMidpoints) {
+			combo.ExpectedInterruption += weight * interruptionMidpoints[level]
+		}
+		combo.LargestShare = math.Max(combo.LargestShare, weight)
+	}
+	return combo, nil
+}
+
+// runCombo implements the combo command: it recommends a diversified mix of
+// instance types covering a vCPU requirement at the lowest current price
+func runCombo(args []string) {
+	comboFlags.Parse(args)
+
+	validArch := *comboArch == "" || *comboArch == "arm64" || *comboArch == "x86_64"
+	if *comboVCPUs <= 0 || *comboRegion == "" || *comboTypes < minComboTypes || *comboTypes > maxComboTypes || len(fleetSubnets()) == 0 || !validArch {
+		fmt.Fprintln(os.Stderr, "usage: combo --vcpus <n> --region <region> [--types 4] [--max-interruption 10-15%] [--arch arm64|x86_64] [--format text|spot-fleet|create-fleet|crossplane|ack|ecs|nomad]")
+		comboFlags.PrintDefaults()
+		os.Exit(2)
+	}
+
+	deals, err := getSpotDeals(*comboRegion)
+	if err != nil {
+		log.Fatalf("Error getting spot deals for region %s: %v", *comboRegion, err)
+	}
+	if *comboArch != "" {
+		var kept []Instance
+		for _, instance := range deals {
+			if instanceArchitecture(instance.InstanceType) == *comboArch {
+				kept = append(kept, instance)
+			}
+		}
+		deals = kept
+	}
+
+	combo, err := recommendCombo(deals, *comboVCPUs, *comboTypes, *comboMaxInterruption)
+	if err != nil {
+		log.Fatalf("No combo for %d vCPUs in %s: %v", *comboVCPUs, *comboRegion, err)
+	}
+
+	var config []byte
+	switch *comboFormat {
+	case "text":
+		printCombo(os.Stdout, combo)
+		return
+	case "spot-fleet":
+		config, err = spotFleetConfig(combo)
+	case "create-fleet":
+		config, err = createFleetConfig(combo)
+	case "crossplane":
+		config, err = crossplaneNodeGroup(combo)
+	case "ack":
+		config, err = ackNodegroup(combo)
+	case "ecs":
+		config, err = ecsAutoScalingGroup(combo)
+	case "nomad":
+		config, err = nomadScalingPolicy(combo)
+	default:
+		log.Fatalf("Unsupported format %q", *comboFormat)
+	}
+	if err != nil {
+		log.Fatalf("Error rendering %s config: %v", *comboFormat, err)
+	}
+	fmt.Println(string(config))
+}
+
+// printCombo writes the picks of a combo as an aligned table followed by its totals
+func printCombo(out io.Writer, combo Combo) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "INSTANCE TYPE\tCOUNT\tVCPUS\tSPOT PRICE\tHOURLY COST\tINTERRUPTION")
+	for _, pick := range combo.Picks {
+		rate := pick.Instance.InterruptionRate
+		if rate == "" {
+			rate = "unknown"
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t$%s\t$%.4f\t%s\n", pick.Instance.InstanceType, pick.Count, pick.VCPUS, pick.Instance.SpotPrice, pick.HourlyCost, rate)
+	}
+	w.Flush()
+
+	fmt.Fprintf(out, "\nTotal: %d vCPUs for $%.4f/hour ($%.6f per vCPU)\n", combo.VCPUS, combo.HourlyCost, combo.HourlyCost/float64(combo.VCPUS))
+	fmt.Fprintf(out, "Expected interruption: about %.1f%%; a single interrupted pool takes down at most %.0f%% of the fleet\n", combo.ExpectedInterruption, combo.LargestShare*100)
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// programName is the binary name completion scripts register for
+const programName = "ec2-spot-finder"
+
+// subcommand describes a subcommand for shell completion
+type subcommand struct {
+	Description string
+	Flags       *flag.FlagSet
+	Args        []string
+}
+
+// subcommands lists the subcommands dispatched by main
+func subcommands() map[string]subcommand {
+	return map[string]subcommand{
+		"check":      {"Exit non-zero unless an instance fits a per-vCPU budget", checkFlags, nil},
+		"ci-runners": {"Rank instance types for self-hosted CI runners", ciRunnersFlags, nil},
+		"combo":      {"Recommend a diversified mix of instance types for a vCPU requirement", comboFlags, nil},
+		"completion": {"Print a shell completion script", nil, []string{"bash", "zsh", "fish"}},
+		"gpu":        {"Compare GPU instance prices per GPU-hour and TFLOP-hour across regions", gpuFlags, nil},
+		"launch":     {"Print an aws-cli or Terraform snippet requesting a spot instance", launchFlags, nil},
+		"serve":      {"Serve the site and a JSON API over the published data", serveFlags, nil},
+		"simulate":   {"Estimate a workload's cost by replaying the price history", simulateFlags, nil},
+		"tui":        {"Explore the published deals in an interactive table", tuiFlags, nil},
+		"version":    {"Print the build version, or check for a newer release", versionFlags, nil},
+		"watch":      {"Refresh a region's deals periodically, highlighting price changes", watchFlags, nil},
+	}
+}
+
+// runCompletion prints the completion script for the requested shell
+func runCompletion(args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: completion bash|zsh|fish")
+		os.Exit(2)
+	}
+
+	switch args[0] {
+	case "bash":
+		fmt.Print(bashCompletion())
+	case "zsh":
+		fmt.Print(zshCompletion())
+	case "fish":
+		fmt.Print(fishCompletion())
+	default:
+		fmt.Fprintf(os.Stderr, "unsupported shell %q\n", args[0])
+		os.Exit(2)
+	}
+}
+
+// sortedSubcommands returns the subcommand names in a stable order
+func sortedSubcommands() []string {
+	var names []string
+	for name := range subcommands() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// flagNames returns the flags of fs prefixed with a double dash
+func flagNames(fs *flag.FlagSet) []string {
+	var names []string
+	if fs != nil {
+		fs.VisitAll(func(f *flag.Flag) {
+			if !hiddenFlags[f.Name] {
+				names = append(names, "--"+f.Name)
+			}
+		})
+	}
+	return names
+}
+
+// isBoolFlag reports whether f takes no value
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// bashCompletion renders a bash completion function
+func bashCompletion() string {
+	var b strings.Builder
+	fn := "_" + strings.ReplaceAll(programName, "-", "_")
+
+	fmt.Fprintf(&b, "%s() {\n", fn)
+	b.WriteString("    local cur=${COMP_WORDS[COMP_CWORD]} cmd= i\n")
+	b.WriteString("    for ((i = 1; i < COMP_CWORD; i++)); do\n")
+	fmt.Fprintf(&b, "        case ${COMP_WORDS[i]} in %s) cmd=${COMP_WORDS[i]}; break ;; esac\n", strings.Join(sortedSubcommands(), "|"))
+	b.WriteString("    done\n")
+	b.WriteString("    case $cmd in\n")
+	for _, name := range sortedSubcommands() {
+		cmd := subcommands()[name]
+		words := append(flagNames(cmd.Flags), cmd.Args...)
+		fmt.Fprintf(&b, "        %s) COMPREPLY=($(compgen -W %q -- \"$cur\")) ;;\n", name, strings.Join(words, " "))
+	}
+	words := append(sortedSubcommands(), flagNames(flag.CommandLine)...)
+	fmt.Fprintf(&b, "        *) COMPREPLY=($(compgen -W %q -- \"$cur\")) ;;\n", strings.Join(words, " "))
+	b.WriteString("    esac\n")
+	b.WriteString("}\n")
+	fmt.Fprintf(&b, "complete -F %s %s\n", fn, programName)
+	return b.String()
+}
+
+// zshFlagSpecs renders the flags of fs as _arguments specifications
+func zshFlagSpecs(fs *flag.FlagSet) []string {
+	var specs []string
+	if fs == nil {
+		return specs
+	}
+	escape := strings.NewReplacer("[", "\\[", "]", "\\]", "'", "'\\''")
+	fs.VisitAll(func(f *flag.Flag) {
+		if hiddenFlags[f.Name] {
+			return
+		}
+		spec := fmt.Sprintf("--%s[%s]", f.Name, escape.Replace(f.Usage))
+		if !isBoolFlag(f) {
+			spec += ":" + f.Name + ":"
+		}
+		specs = append(specs, "'"+spec+"'")
+	})
+	return specs
+}
+
+// zshCompletion renders a zsh completion function
+func zshCompletion() string {
+	var b strings.Builder
+	fn := "_" + strings.ReplaceAll(programName, "-", "_")
+
+	fmt.Fprintf(&b, "#compdef %s\n\n", programName)
+	fmt.Fprintf(&b, "%s() {\n", fn)
+	b.WriteString("    case ${words[2]} in\n")
+	for _, name := range sortedSubcommands() {
+		cmd := subcommands()[name]
+		specs := zshFlagSpecs(cmd.Flags)
+		if len(cmd.Args) > 0 {
+			specs = append(specs, fmt.Sprintf("'2:%s:(%s)'", name, strings.Join(cmd.Args, " ")))
+		}
+		fmt.Fprintf(&b, "        %s) _arguments %s ;;\n", name, strings.Join(specs, " "))
+	}
+	var described []string
+	for _, name := range sortedSubcommands() {
+		described = append(described, fmt.Sprintf("%s\\:'%s'", name, subcommands()[name].Description))
+	}
+	specs := append([]string{fmt.Sprintf("\"1:command:((%s))\"", strings.Join(described, " "))}, zshFlagSpecs(flag.CommandLine)...)
+	fmt.Fprintf(&b, "        *) _arguments %s ;;\n", strings.Join(specs, " "))
+	b.WriteString("    esac\n")
+	b.WriteString("}\n\n")
+	fmt.Fprintf(&b, "compdef %s %s\n", fn, programName)
+	return b.String()
+}
+
+// fishFlagLines renders one complete command per flag of fs
+func fishFlagLines(b *strings.Builder, fs *flag.FlagSet, condition string) {
+	if fs == nil {
+		return
+	}
+	fs.VisitAll(func(f *flag.Flag) {
+		if hiddenFlags[f.Name] {
+			return
+		}
+		line := fmt.Sprintf("complete -c %s -n '%s' -l %s", programName, condition, f.Name)
+		if !isBoolFlag(f) {
+			line += " -r"
+		}
+		fmt.Fprintf(b, "%s -d %q\n", line, f.Usage)
+	})
+}
+
+// fishCompletion renders fish complete commands
+func fishCompletion() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "complete -c %s -f\n", programName)
+	for _, name := range sortedSubcommands() {
+		fmt.Fprintf(&b, "complete -c %s -n '__fish_use_subcommand' -a %s -d %q\n", programName, name, subcommands()[name].Description)
+	}
+	fishFlagLines(&b, flag.CommandLine, "__fish_use_subcommand")
+	for _, name := range sortedSubcommands() {
+		cmd := subcommands()[name]
+		condition := "__fish_seen_subcommand_from " + name
+		fishFlagLines(&b, cmd.Flags, condition)
+		if len(cmd.Args) > 0 {
+			fmt.Fprintf(&b, "complete -c %s -n '%s' -a %q\n", programName, condition, strings.Join(cmd.Args, " "))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+	_ "time/tzdata" // resolve timezone names in minimal containers too
+)
+
+// defaultConfigFile is read from the working directory unless SPOT_FINDER_CONFIG names another file
+const defaultConfigFile = "config.json"
+
+// Config holds the optional settings read from the JSON config file
+type Config struct {
+	// InstanceFilterFile is the allow/deny list of instance types, relative to the config file
+	InstanceFilterFile string `json:"instance_filter_file"`
+	// PolicyFile holds the organization's policy rules, relative to the config file
+	PolicyFile string `json:"policy_file"`
+	// AlertsFile configures the serve daemon's price alerts, relative to the config file
+	AlertsFile string `json:"alerts_file"`
+	// Schedule sets the serve daemon's refresh windows and quiet hours
+	Schedule Schedule `json:"schedule"`
+	// MinInstancesPerRegion relaxes the savings threshold for regions with fewer deals
+	MinInstancesPerRegion int `json:"min_instances_per_region"`
+	// SavingsBuckets are the ascending savings-rate boundaries of the published tiers
+	SavingsBuckets []int `json:"savings_buckets"`
+	// RegionRanking and GlobalRanking name the strategies ordering each
+	// region's deals and the global top deals
+	RegionRanking string `json:"region_ranking"`
+	GlobalRanking string `json:"global_ranking"`
+	// Scoring is the formula of the "formula" ranking, lowest score first
+	Scoring string `json:"scoring"`
+	// TopMaxPerFamily and TopMaxPerRegion limit the global top deals of one
+	// instance family and of one region, so the list compares alternatives; 0 is no limit
+	TopMaxPerFamily int `json:"top_max_per_family"`
+	TopMaxPerRegion int `json:"top_max_per_region"`
+	// Providers are the clouds fetched on every refresh, each limited to ProviderTimeout
+	Providers       []string `json:"providers"`
+	ProviderTimeout duration `json:"provider_timeout"`
+	// EURUSDRate converts prices of providers billing in euros
+	EURUSDRate float64 `json:"eur_usd_rate"`
+	// BaselinePricePerVCPU is a reference cost, e.g. on-premises, the top deals are compared to
+	BaselinePricePerVCPU float64 `json:"baseline_price_per_vcpu"`
+	// ExcludeDeprecated drops previous-generation instances instead of only marking them
+	ExcludeDeprecated bool `json:"exclude_deprecated"`
+	// CreditPrograms maps credit or free-tier programs to the instance type
+	// patterns they cover, so instances can be annotated with them
+	CreditPrograms map[string][]string `json:"credit_programs"`
+	// Bundle adds the monthly cost of attached storage and egress to the top deals
+	Bundle *BundleConfig `json:"bundle"`
+	// DisplayTimezone is the IANA timezone the update time is also published in
+	DisplayTimezone string `json:"display_timezone"`
+	// RefreshInterval is how often the data is expected to refresh, published for the site
+	RefreshInterval duration `json:"refresh_interval"`
+	// StaleAfter is the data age past which the site warns that prices are old
+	StaleAfter duration `json:"stale_after"`
+	// PriceBucketWidth publishes spot prices as ranges of this width instead of exact values
+	PriceBucketWidth float64 `json:"price_bucket_width"`
+	// License is the license of the published data, e.g. "CC-BY-4.0"
+	License string `json:"license"`
+	// Attributions override the built-in credits of upstream sources, keyed by source
+	Attributions map[string]Attribution `json:"attributions"`
+	// Profiles are named team-specific queries, each written to ProfilesDir/<name>.json
+	Profiles    map[string]Query `json:"profiles"`
+	ProfilesDir string           `json:"profiles_dir"`
+	// Environments are deployment stages selected with --env, e.g. staging and prod
+	Environments map[string]Environment `json:"environments"`
+	// CacheTTLs override --cache-ttl per upstream host, e.g. {"ec2.shop": "10m"}
+	CacheTTLs map[string]duration `json:"cache_ttls"`
+}
+
+// duration is a time.Duration written as a string such as "10m" in the config file
+type duration time.Duration
+
+func (d *duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = duration(parsed)
+	return nil
+}
+
+// InstanceFilter is a user-maintained allow/deny list of instance type
+// patterns such as "c6g.*" or "*.metal"; an empty allow list permits all types
+type InstanceFilter struct {
+	Allow []string `json:"allow"`
+	Deny  []string `json:"deny"`
+}
+
+var (
+	config           Config
+	instanceFilter   InstanceFilter
+	regionStrategy   Strategy = CheapestPerVCPU{}
+	globalStrategy   Strategy = CheapestPerVCPU{}
+	enabledProviders []Provider
+	displayLocation  = time.UTC
+)
+
+// configPath returns the config file location
+func configPath() string {
+	if p := os.Getenv("SPOT_FINDER_CONFIG"); p != "" {
+		return p
+	}
+	return defaultConfigFile
+}
+
+// loadConfig reads the config file and the files it references; missing
+// files leave the defaults in place
+func loadConfig(filename string) error {
+	config = Config{
+		InstanceFilterFile:    "instance_filter.json",
+		PolicyFile:            "policy.json",
+		AlertsFile:            "alerts.json",
+		MinInstancesPerRegion: 1,
+		SavingsBuckets:        []int{50, 60, 70},
+		RegionRanking:         "cheapest_per_vcpu",
+		GlobalRanking:         "cheapest_per_vcpu",
+		TopMaxPerRegion:       1,
+		Providers:             []string{"aws"},
+		ProviderTimeout:       duration(10 * time.Minute),
+		EURUSDRate:            1.1,
+		ProfilesDir:           "docs/profiles",
+		StaleAfter:            duration(26 * time.Hour),
+		DisplayTimezone:       "UTC",
+		RefreshInterval:       duration(24 * time.Hour),
+	}
+	if err := readJSONFile(filename, &config); err != nil {
+		return err
+	}
+	return resolveConfig(filename)
+}
+
+// resolveConfig loads the files the config references and derives the
+// settings built from it
+func resolveConfig(filename string) error {
+	policy = Policy{}
+	if config.PolicyFile != "" {
+		policyPath := filepath.Join(filepath.Dir(filename), config.PolicyFile)
+		if err := readJSONFile(policyPath, &policy); err != nil {
+			return err
+		}
+		if err := policy.validate(); err != nil {
+			return fmt.Errorf("%s: %w", policyPath, err)
+		}
+	}
+
+	alerts = Alerts{MinDropPercent: 10, Cooldown: duration(6 * time.Hour), DigestSize: 20}
+	if config.AlertsFile != "" {
+		alertsPath := filepath.Join(filepath.Dir(filename), config.AlertsFile)
+		if err := readJSONFile(alertsPath, &alerts); err != nil {
+			return err
+		}
+		if err := alerts.validate(); err != nil {
+			return fmt.Errorf("%s: %w", alertsPath, err)
+		}
+	}
+	if err := config.Schedule.validate(); err != nil {
+		return fmt.Errorf("%s: schedule: %w", filename, err)
+	}
+
+	if err := validateCreditPrograms(config.CreditPrograms); err != nil {
+		return fmt.Errorf("%s: %w", filename, err)
+	}
+	if err := validateProfiles(config.Profiles); err != nil {
+		return fmt.Errorf("%s: %w", filename, err)
+	}
+
+	delete(strategies, "formula")
+	if config.Scoring != "" {
+		formula, err := parseScoreFormula(config.Scoring)
+		if err != nil {
+			return fmt.Errorf("%s: %w", filename, err)
+		}
+		strategies["formula"] = formula
+	}
+
+	var err error
+	if regionStrategy, err = strategyNamed(config.RegionRanking); err != nil {
+		return fmt.Errorf("%s: region_ranking: %w", filename, err)
+	}
+	if globalStrategy, err = strategyNamed(config.GlobalRanking); err != nil {
+		return fmt.Errorf("%s: global_ranking: %w", filename, err)
+	}
+	if config.TopMaxPerFamily < 0 || config.TopMaxPerRegion < 0 {
+		return fmt.Errorf("%s: top_max_per_family and top_max_per_region must not be negative", filename)
+	}
+	if displayLocation, err = time.LoadLocation(config.DisplayTimezone); err != nil {
+		return fmt.Errorf("%s: display_timezone: %w", filename, err)
+	}
+	enabledProviders = nil
+	for _, name := range config.Providers {
+		provider, err := providerNamed(name)
+		if err != nil {
+			return fmt.Errorf("%s: providers: %w", filename, err)
+		}
+		enabledProviders = append(enabledProviders, provider)
+	}
+
+	instanceFilter = InstanceFilter{}
+	if config.InstanceFilterFile != "" {
+		filterPath := filepath.Join(filepath.Dir(filename), config.InstanceFilterFile)
+		if err := readJSONFile(filterPath, &instanceFilter); err != nil {
+			return err
+		}
+		for _, pattern := range append(instanceFilter.Allow, instanceFilter.Deny...) {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("%s: invalid pattern %q: %w", filterPath, pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// readJSONFile decodes filename into v, leaving v untouched if the file does not exist
+func readJSONFile(filename string, v interface{}) error {
+	content, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(content, v); err != nil {
+		return fmt.Errorf("%s: %w", filename, err)
+	}
+	return nil
+}
+
+// Permits reports whether instanceType passes the allow and deny lists
+func (f InstanceFilter) Permits(instanceType string) bool {
+	for _, pattern := range f.Deny {
+		if ok, _ := path.Match(pattern, instanceType); ok {
+			return false
+		}
+	}
+	if len(f.Allow) == 0 {
+		return true
+	}
+	for _, pattern := range f.Allow {
+		if ok, _ := path.Match(pattern, instanceType); ok {
+			return true
+		}
+	}
+	return false
+}
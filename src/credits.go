@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"sort"
+)
+
+// validateCreditPrograms checks the instance type patterns of each credit program
+func validateCreditPrograms(programs map[string][]string) error {
+	for name, patterns := range programs {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("credit_programs: %s: invalid pattern %q: %w", name, pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// applyCredits lists, on each instance, the configured credit and
+// free-tier programs covering its type
+func applyCredits(instances []Instance) {
+	if len(config.CreditPrograms) == 0 {
+		return
+	}
+	names := make([]string, 0, len(config.CreditPrograms))
+	for name := range config.CreditPrograms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for i := range instances {
+		instances[i].Credits = nil
+		for _, name := range names {
+			if matchesAny(config.CreditPrograms[name], instances[i].InstanceType) {
+				instances[i].Credits = append(instances[i].Credits, name)
+			}
+		}
+	}
+}
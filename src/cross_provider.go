@@ -0,0 +1,118 @@
+package main
+
+import (
+	"math"
+	"sort"
+)
+
+// ProviderData holds the regional deals of a provider other than AWS, whose
+// deals stay at the top level of SpotData for existing consumers
+type ProviderData struct {
+	Regions     map[string][]Instance `json:"regions"`
+	Sources     map[string]string     `json:"sources,omitempty"`
+	LastUpdated string                `json:"last_updated,omitempty"` // last successful fetch of the provider
+}
+
+// CrossProviderDeal is a region's best deal in a schema common to all
+// providers, so clouds with different instance naming can be compared
+type CrossProviderDeal struct {
+	Provider         string  `json:"provider"`
+	Region           string  `json:"region"`
+	InstanceType     string  `json:"instanceType"`
+	Category         string  `json:"category,omitempty"`
+	VCPUS            int     `json:"vcpus"`
+	MemoryGiB        float64 `json:"memoryGiB"`
+	Price            float64 `json:"price"`
+	PricePerVCPU     float64 `json:"pricePerVCPU"`
+	PricePerGiB      float64 `json:"pricePerGiB"`
+	InterruptionRate string  `json:"interruptionRate,omitempty"`
+	BaselineRatio    float64 `json:"baselineRatio,omitempty"`
+}
+
+// crossProviderDeal normalizes a provider's instance
+func crossProviderDeal(provider, region string, instance Instance) CrossProviderDeal {
+	price := parsePrice(instance.SpotPrice)
+	deal := CrossProviderDeal{
+		Provider:         provider,
+		Region:           region,
+		InstanceType:     instance.InstanceType,
+		Category:         classifyInstance(provider, instance.InstanceType),
+		VCPUS:            instance.VCPUS,
+		MemoryGiB:        parseLeadingNumber(instance.Memory),
+		Price:            price,
+		PricePerVCPU:     pricePerVCPU(instance),
+		InterruptionRate: instance.InterruptionRate,
+	}
+	if deal.MemoryGiB > 0 {
+		deal.PricePerGiB = price / deal.MemoryGiB
+	}
+	return deal
+}
+
+// crossProviderTop ranks the best deal of every region of every spot
+// provider with the global strategy and keeps the top 5
+func crossProviderTop(data SpotData) []CrossProviderDeal {
+	sections := map[string]map[string][]Instance{"aws": data.Regions}
+	for name, provider := range data.Providers {
+		if !flatRateProviders[name] {
+			sections[name] = provider.Regions
+		}
+	}
+
+	type candidate struct {
+		deal     CrossProviderDeal
+		instance Instance
+	}
+	var candidates []candidate
+	for provider, regions := range sections {
+		for region, instances := range regions {
+			if len(instances) == 0 {
+				continue
+			}
+			best := bestInstance(instances, globalStrategy)
+			if math.IsInf(pricePerVCPU(best), 1) {
+				continue
+			}
+			candidates = append(candidates, candidate{crossProviderDeal(provider, region, best), best})
+		}
+	}
+
+	// Order ties deterministically since map iteration is random
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if globalStrategy.Less(a.instance, b.instance) {
+			return true
+		}
+		if globalStrategy.Less(b.instance, a.instance) {
+			return false
+		}
+		if a.deal.Provider != b.deal.Provider {
+			return a.deal.Provider < b.deal.Provider
+		}
+		return a.deal.Region < b.deal.Region
+	})
+
+	var top []CrossProviderDeal
+	for i := 0; i < len(candidates) && i < 5; i++ {
+		top = append(top, candidates[i].deal)
+	}
+	return top
+}
+
+// mergeProviders merges fresh provider sections into existing ones like the
+// AWS regions, keeping the previous data of providers that failed this run
+func mergeProviders(existing, new map[string]ProviderData) map[string]ProviderData {
+	if len(existing) == 0 && len(new) == 0 {
+		return nil
+	}
+	merged := make(map[string]ProviderData, len(existing)+len(new))
+	for name, data := range existing {
+		merged[name] = data
+	}
+	for name, data := range new {
+		old := merged[name]
+		section := mergeSpotData(SpotData{Regions: old.Regions, Sources: old.Sources}, SpotData{Regions: data.Regions, Sources: data.Sources})
+		merged[name] = ProviderData{Regions: section.Regions, Sources: section.Sources, LastUpdated: data.LastUpdated}
+	}
+	return merged
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"sort"
+	"strconv"
+)
+
+// encodeSpotDataCSV flattens the regional instances into CSV rows
+func encodeSpotDataCSV(data SpotData) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+
+	if err := w.Write([]string{"region", "instance_type", "vcpus", "memory", "spot_saving_rate", "spot_price"}); err != nil {
+		return nil, err
+	}
+
+	regions := make([]string, 0, len(data.Regions))
+	for region := range data.Regions {
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+
+	for _, region := range regions {
+		for _, instance := range data.Regions[region] {
+			record := []string{
+				region,
+				instance.InstanceType,
+				strconv.Itoa(instance.VCPUS),
+				instance.Memory,
+				instance.SpotSavingRate,
+				instance.SpotPrice,
+			}
+			if err := w.Write(record); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
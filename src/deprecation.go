@@ -0,0 +1,32 @@
+package main
+
+import "strings"
+
+// previousGenerationFamilies are EC2 families AWS lists as previous
+// generation or has retired. They keep running but get no new capacity or
+// features, so they make poor picks for new workloads.
+var previousGenerationFamilies = map[string]bool{
+	"a1": true, "c1": true, "c3": true, "c4": true, "cc2": true, "cg1": true, "cr1": true,
+	"d2": true, "g2": true, "g3": true, "g3s": true, "hi1": true, "hs1": true, "i2": true,
+	"m1": true, "m2": true, "m3": true, "m4": true, "p2": true, "r3": true, "r4": true, "t1": true,
+}
+
+// isDeprecated reports whether an EC2 instance type belongs to a previous-generation family
+func isDeprecated(instanceType string) bool {
+	family, _, _ := strings.Cut(strings.ToLower(instanceType), ".")
+	return previousGenerationFamilies[family]
+}
+
+// applyDeprecations marks previous-generation instances, or drops them when
+// the config excludes them from rankings
+func applyDeprecations(instances []Instance) []Instance {
+	kept := instances[:0]
+	for _, instance := range instances {
+		instance.Deprecated = isDeprecated(instance.InstanceType)
+		if instance.Deprecated && config.ExcludeDeprecated {
+			continue
+		}
+		kept = append(kept, instance)
+	}
+	return kept
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// PriceChange describes an instance whose spot price changed between two datasets
+type PriceChange struct {
+	Region       string `json:"region"`
+	InstanceType string `json:"instanceType"`
+	OldPrice     string `json:"oldPrice"`
+	NewPrice     string `json:"newPrice"`
+	// ChangePercent is the relative price change, e.g. -12.5 for a 12.5% drop
+	ChangePercent float64 `json:"changePercent"`
+}
+
+// RegionInstance identifies an instance listed in a region
+type RegionInstance struct {
+	Region       string `json:"region"`
+	InstanceType string `json:"instanceType"`
+	SpotPrice    string `json:"price"`
+}
+
+// SpotDiff summarizes the differences between two spot datasets
+type SpotDiff struct {
+	AddedRegions []string         `json:"addedRegions"`
+	Added        []RegionInstance `json:"added"`
+	Removed      []RegionInstance `json:"removed"`
+	Changed      []PriceChange    `json:"changed"`
+}
+
+// diffSpotData compares the regional instances of two datasets
+func diffSpotData(old, new SpotData) SpotDiff {
+	var diff SpotDiff
+
+	regions := make([]string, 0, len(new.Regions))
+	for region := range new.Regions {
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+
+	for _, region := range regions {
+		oldInstances, ok := old.Regions[region]
+		if !ok {
+			diff.AddedRegions = append(diff.AddedRegions, region)
+		}
+
+		oldPrices := make(map[string]string)
+		for _, instance := range oldInstances {
+			oldPrices[instance.InstanceType] = instance.SpotPrice
+		}
+
+		newTypes := make(map[string]bool)
+		for _, instance := range new.Regions[region] {
+			newTypes[instance.InstanceType] = true
+			oldPrice, existed := oldPrices[instance.InstanceType]
+			if !existed {
+				diff.Added = append(diff.Added, RegionInstance{region, instance.InstanceType, instance.SpotPrice})
+			} else if oldPrice != instance.SpotPrice {
+				diff.Changed = append(diff.Changed, PriceChange{region, instance.InstanceType, oldPrice, instance.SpotPrice, changePercent(oldPrice, instance.SpotPrice)})
+			}
+		}
+
+		for _, instance := range oldInstances {
+			if !newTypes[instance.InstanceType] {
+				diff.Removed = append(diff.Removed, RegionInstance{region, instance.InstanceType, instance.SpotPrice})
+			}
+		}
+	}
+
+	return diff
+}
+
+// changePercent returns the relative change between two prices, or zero
+// when the old price is unknown
+func changePercent(oldPrice, newPrice string) float64 {
+	old := parsePrice(oldPrice)
+	if old == 0 {
+		return 0
+	}
+	return math.Round((parsePrice(newPrice)-old)/old*10000) / 100
+}
+
+// Empty reports whether the diff contains no changes
+func (d SpotDiff) Empty() bool {
+	return len(d.AddedRegions) == 0 && len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// Markdown renders the diff as a short Markdown summary
+func (d SpotDiff) Markdown() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "- %d new regions\n", len(d.AddedRegions))
+	fmt.Fprintf(&b, "- %d instances added\n", len(d.Added))
+	fmt.Fprintf(&b, "- %d instances removed\n", len(d.Removed))
+	fmt.Fprintf(&b, "- %d price changes\n", len(d.Changed))
+
+	if len(d.AddedRegions) > 0 {
+		fmt.Fprintf(&b, "\nNew regions: %s\n", strings.Join(d.AddedRegions, ", "))
+	}
+
+	if len(d.Changed) > 0 {
+		// Limit the table so large updates still fit in a pull request body
+		const maxRows = 50
+		b.WriteString("\n| Region | Instance Type | Old Price | New Price |\n|---|---|---|---|\n")
+		for i, change := range d.Changed {
+			if i == maxRows {
+				fmt.Fprintf(&b, "\n_…and %d more price changes._\n", len(d.Changed)-maxRows)
+				break
+			}
+			fmt.Fprintf(&b, "| %s | %s | $%s | $%s |\n", change.Region, change.InstanceType, change.OldPrice, change.NewPrice)
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+)
+
+var diffFile = flag.String("diff-file", "docs/diff_latest.json", "write the changes of each update to this file for the site; empty disables it")
+
+// DiffSummary counts the entries of each kind in a diff
+type DiffSummary struct {
+	AddedRegions int `json:"addedRegions"`
+	Added        int `json:"added"`
+	Removed      int `json:"removed"`
+	Changed      int `json:"changed"`
+}
+
+// LatestDiff is the published diff between the previous and current datasets
+type LatestDiff struct {
+	From    string      `json:"from"` // last_updated of the previous dataset
+	To      string      `json:"to"`
+	Summary DiffSummary `json:"summary"`
+	SpotDiff
+	License     string        `json:"license,omitempty"`
+	Attribution []Attribution `json:"attribution,omitempty"`
+}
+
+// writeDiffFile publishes the changes between the previous and new datasets,
+// using empty lists rather than null so the frontend can iterate directly
+func writeDiffFile(filename string, old, new SpotData, diff SpotDiff) error {
+	latest := LatestDiff{
+		From: old.LastUpdated,
+		To:   new.LastUpdated,
+		Summary: DiffSummary{
+			AddedRegions: len(diff.AddedRegions),
+			Added:        len(diff.Added),
+			Removed:      len(diff.Removed),
+			Changed:      len(diff.Changed),
+		},
+		SpotDiff:    diff,
+		License:     new.License,
+		Attribution: new.Attribution,
+	}
+	if latest.AddedRegions == nil {
+		latest.AddedRegions = []string{}
+	}
+	if latest.Added == nil {
+		latest.Added = []RegionInstance{}
+	}
+	if latest.Removed == nil {
+		latest.Removed = []RegionInstance{}
+	}
+	if latest.Changed == nil {
+		latest.Changed = []PriceChange{}
+	}
+
+	content, err := json.MarshalIndent(latest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeJSONFile(filename, content)
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// mockUpstreams serves the upstream endpoints from testdata/upstream: the
+// locations list, ec2.shop's prices per region, the AWS spot price feed and
+// the Spot Instance Advisor. Regions listed in failing answer 502.
+func mockUpstreams(t *testing.T, failing ...string) {
+	t.Helper()
+	failed := make(map[string]bool)
+	for _, region := range failing {
+		failed[region] = true
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ec2shop", func(w http.ResponseWriter, r *http.Request) {
+		region := r.URL.Query().Get("region")
+		if r.Header.Get("accept") != "json" || failed[region] {
+			http.Error(w, "bad gateway", http.StatusBadGateway)
+			return
+		}
+		http.ServeFile(w, r, filepath.Join("testdata", "upstream", "ec2shop-"+region+".json"))
+	})
+	for _, name := range []string{"locations.json", "spot.js", "spot-advisor-data.json"} {
+		path := filepath.Join("testdata", "upstream", name)
+		mux.HandleFunc("/"+name, func(w http.ResponseWriter, r *http.Request) {
+			http.ServeFile(w, r, path)
+		})
+	}
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	setVar(t, &ec2ShopURL, server.URL+"/ec2shop")
+	setVar(t, &locationsURL, server.URL+"/locations.json")
+	setVar(t, &awsSpotFeedURL, server.URL+"/spot.js")
+	setVar(t, &spotAdvisorURL, server.URL+"/spot-advisor-data.json")
+
+	// Fetch the interruption rates from the mock rather than an earlier test
+	spotAdvisorCache.Lock()
+	spotAdvisorCache.fetched = time.Time{}
+	spotAdvisorCache.Unlock()
+}
+
+// setVar sets a package variable for the duration of the test
+func setVar(t testing.TB, v *string, value string) {
+	t.Helper()
+	old := *v
+	*v = value
+	t.Cleanup(func() { *v = old })
+}
+
+// setFlag sets a command-line flag for the duration of the test
+func setFlag(t *testing.T, name, value string) {
+	t.Helper()
+	old := flag.Lookup(name).Value.String()
+	if err := flag.Set(name, value); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { flag.Set(name, old) })
+}
+
+// TestRefreshEndToEnd runs refresh against the mock upstreams, fetching,
+// merging into the published dataset and writing it, and compares the
+// dataset and its diff with their golden files
+func TestRefreshEndToEnd(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing bool     // start from the fixture dataset
+		failing  []string // regions ec2.shop fails for
+	}{
+		{name: "first_run"},
+		{name: "merge", existing: true},
+		{name: "region_failure", existing: true, failing: []string{"eu-west-1"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mockUpstreams(t, test.failing...)
+
+			// Run the day after the fixture dataset was published
+			now := clock
+			clock = func() time.Time { return testNow.Add(24 * time.Hour) }
+			t.Cleanup(func() { clock = now })
+
+			dir := t.TempDir()
+			setVar(t, &dataFile, filepath.Join(dir, "spot_data.json"))
+			for name, file := range map[string]string{
+				"history":         "price_history.jsonl",
+				"status-file":     "status.json",
+				"heartbeat-file":  "heartbeat.json",
+				"diff-file":       "diff_latest.json",
+				"locations-cache": "locations.json",
+			} {
+				setFlag(t, name, filepath.Join(dir, file))
+			}
+			setFlag(t, "checkpoint", "")
+
+			if test.existing {
+				content, err := os.ReadFile(filepath.Join("testdata", "spot_data.json"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(dataFile, content, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			merge, err := mergeFunc("merge")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := refresh(merge, nil); err != nil {
+				t.Fatalf("refresh: %v", err)
+			}
+
+			for _, file := range []string{"spot_data.json", "diff_latest.json"} {
+				got, err := os.ReadFile(filepath.Join(dir, file))
+				if err != nil {
+					t.Fatal(err)
+				}
+				checkGolden(t, "e2e_"+test.name+"_"+file, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"sort"
+)
+
+var (
+	envName      = flag.String("env", "", "deployment environment from the config's environments, e.g. staging or prod")
+	serveEnvName = serveFlags.String("env", "", "deployment environment from the config's environments, e.g. staging or prod")
+)
+
+// Environment overrides settings for one deployment stage, so changes can be
+// validated on a staging dataset before they reach the public site
+type Environment struct {
+	// DataFile replaces docs/spot_data.json as the published dataset
+	DataFile string `json:"data_file"`
+	// Flags are defaults for command-line flags such as "history", "diff-file"
+	// or "gcs-bucket"; flags given on the command line still win
+	Flags map[string]string `json:"flags"`
+	// Config overlays keys of the config file, e.g. alerts_file or profiles_dir
+	Config json.RawMessage `json:"config"`
+}
+
+// applyEnvironment switches to the environment called name, setting the
+// flags of fs it overrides that were not given on the command line
+func applyEnvironment(name string, fs *flag.FlagSet) error {
+	env, ok := config.Environments[name]
+	if !ok {
+		var names []string
+		for name := range config.Environments {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("unknown environment %q (configured: %v)", name, names)
+	}
+
+	given := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { given[f.Name] = true })
+	for flagName, value := range env.Flags {
+		if flag.CommandLine.Lookup(flagName) == nil && serveFlags.Lookup(flagName) == nil {
+			return fmt.Errorf("environment %s: unknown flag %q", name, flagName)
+		}
+		// Flags of other commands apply when those commands run
+		if fs.Lookup(flagName) == nil || given[flagName] {
+			continue
+		}
+		if err := fs.Set(flagName, value); err != nil {
+			return fmt.Errorf("environment %s: flag %s: %w", name, flagName, err)
+		}
+	}
+
+	if env.DataFile != "" {
+		dataFile = env.DataFile
+	}
+	if len(env.Config) > 0 {
+		if err := json.Unmarshal(env.Config, &config); err != nil {
+			return fmt.Errorf("environment %s: config: %w", name, err)
+		}
+		if err := resolveConfig(configPath()); err != nil {
+			return fmt.Errorf("environment %s: %w", name, err)
+		}
+	}
+	return nil
+}
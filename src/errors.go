@@ -0,0 +1,41 @@
+package main
+
+import "errors"
+
+// Failure classes wrapped with %w by the fetch and publish paths, so callers
+// can tell a retryable outage from bad upstream data or a failed upload
+var (
+	// ErrUpstreamUnavailable means an upstream could not be reached, answered
+	// with an error status or did not answer in time; retrying later may work
+	ErrUpstreamUnavailable = errors.New("upstream unavailable")
+	// ErrDecode means an upstream response was not in the expected format
+	ErrDecode = errors.New("malformed upstream response")
+	// ErrValidation means an upstream response decoded but failed the sanity checks
+	ErrValidation = errors.New("upstream data failed validation")
+	// ErrPublish means the data could not be written to a release, pull request or sink
+	ErrPublish = errors.New("publishing failed")
+)
+
+// Exit statuses of a failed refresh, one per failure class
+const (
+	exitFailure             = 1
+	exitUpstreamUnavailable = 3
+	exitDecode              = 4
+	exitValidation          = 5
+	exitPublish             = 6
+)
+
+// exitCode maps a refresh error to the exit status of its failure class
+func exitCode(err error) int {
+	switch {
+	case errors.Is(err, ErrUpstreamUnavailable):
+		return exitUpstreamUnavailable
+	case errors.Is(err, ErrDecode):
+		return exitDecode
+	case errors.Is(err, ErrValidation):
+		return exitValidation
+	case errors.Is(err, ErrPublish):
+		return exitPublish
+	}
+	return exitFailure
+}
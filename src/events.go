@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+var (
+	eventsFile   = flag.String("events", "", "record price spikes and crashes detected in the price history in this file")
+	spikePercent = flag.Float64("spike-percent", 30, "price move, in percent, recorded as a spike or crash in --events")
+	spikeWindow  = flag.Duration("spike-window", 36*time.Hour, "time within which a price move counts as a spike or crash; longer than the refresh interval, so it covers the previous run even when that ran late")
+)
+
+// maxEvents is the number of events the event log keeps, newest first
+const maxEvents = 1000
+
+// PriceEvent is a spot price that moved more than --spike-percent within
+// --spike-window
+type PriceEvent struct {
+	Kind          string  `json:"kind"` // "spike" or "crash"
+	Region        string  `json:"region"`
+	InstanceType  string  `json:"instance_type"`
+	Before        string  `json:"before"`
+	BeforeTime    string  `json:"before_time"`
+	After         string  `json:"after"`
+	AfterTime     string  `json:"after_time"`
+	ChangePercent float64 `json:"change_percent"`
+}
+
+// EventLog is the event history of the spot market
+type EventLog struct {
+	LastUpdated string       `json:"last_updated"`
+	Events      []PriceEvent `json:"events"` // newest first
+}
+
+// key identifies an event, so a move seen again by later runs while its
+// starting price is still in the window is recorded once
+func (e PriceEvent) key() string {
+	return e.Region + "/" + e.InstanceType + "/" + e.Kind + "/" + e.BeforeTime
+}
+
+// detectPriceEvents compares every fetched price with the observations of
+// the same instance in the window before it, and reports the largest move
+// of each instance when it reaches percent
+func detectPriceEvents(data SpotData, observations []Observation, window time.Duration, percent float64) []PriceEvent {
+	now, err := time.Parse(time.RFC3339, data.LastUpdated)
+	if err != nil {
+		return nil
+	}
+
+	current := make(map[string]Instance)
+	for region, instances := range data.Regions {
+		for _, instance := range instances {
+			current[region+"/"+instance.InstanceType] = instance
+		}
+	}
+
+	largest := make(map[string]PriceEvent)
+	for _, observation := range observations {
+		t, err := time.Parse(time.RFC3339, observation.Time)
+		if err != nil || !t.Before(now) || now.Sub(t) > window {
+			continue
+		}
+		key := observation.Region + "/" + observation.InstanceType
+		instance, ok := current[key]
+		if !ok {
+			continue
+		}
+		before, err := strconv.ParseFloat(observation.Price, 64)
+		after := parsePrice(instance.SpotPrice)
+		if err != nil || before <= 0 || after <= 0 {
+			continue
+		}
+
+		change := math.Round((after-before)/before*1000) / 10
+		if math.Abs(change) < percent || math.Abs(change) <= math.Abs(largest[key].ChangePercent) {
+			continue
+		}
+		kind := "spike"
+		if change < 0 {
+			kind = "crash"
+		}
+		largest[key] = PriceEvent{
+			Kind:          kind,
+			Region:        observation.Region,
+			InstanceType:  observation.InstanceType,
+			Before:        observation.Price,
+			BeforeTime:    observation.Time,
+			After:         instance.SpotPrice,
+			AfterTime:     data.LastUpdated,
+			ChangePercent: change,
+		}
+	}
+
+	events := make([]PriceEvent, 0, len(largest))
+	for _, event := range largest {
+		events = append(events, event)
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if a, b := math.Abs(events[i].ChangePercent), math.Abs(events[j].ChangePercent); a != b {
+			return a > b
+		}
+		return events[i].Region+events[i].InstanceType < events[j].Region+events[j].InstanceType
+	})
+	return events
+}
+
+// updateEventLog adds a run's events to the event log, skipping those
+// already recorded, and keeps the newest maxEvents
+func updateEventLog(filename string, events []PriceEvent, lastUpdated string) error {
+	var eventLog EventLog
+	content, err := os.ReadFile(filename)
+	if err == nil {
+		if err := json.Unmarshal(content, &eventLog); err != nil {
+			return err
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	recorded := make(map[string]bool, len(eventLog.Events))
+	for _, event := range eventLog.Events {
+		recorded[event.key()] = true
+	}
+	var added []PriceEvent
+	for _, event := range events {
+		if !recorded[event.key()] {
+			added = append(added, event)
+		}
+	}
+
+	eventLog.LastUpdated = lastUpdated
+	eventLog.Events = append(added, eventLog.Events...)
+	if len(eventLog.Events) > maxEvents {
+		eventLog.Events = eventLog.Events[:maxEvents]
+	}
+	if eventLog.Events == nil {
+		eventLog.Events = []PriceEvent{}
+	}
+	content, err = json.MarshalIndent(eventLog, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeJSONFile(filename, content)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDetectPriceEvents(t *testing.T) {
+	now := testNow
+	data := SpotData{
+		LastUpdated: now.Format(time.RFC3339),
+		Regions: map[string][]Instance{
+			"eu-west-1": {
+				{InstanceType: "c6g.4xlarge", SpotPrice: "0.3000"},
+				{InstanceType: "m6g.4xlarge", SpotPrice: "0.1000"},
+				{InstanceType: "r6g.4xlarge", SpotPrice: "0.2100"},
+			},
+		},
+	}
+	observation := func(age time.Duration, instanceType, price string) Observation {
+		return Observation{Time: now.Add(-age).Format(time.RFC3339), Region: "eu-west-1", InstanceType: instanceType, Price: price}
+	}
+
+	// The daily schedule starts runs a little late, so the previous
+	// observation is usually slightly more than 24 hours old
+	late := 24*time.Hour + 7*time.Minute
+	observations := []Observation{
+		observation(late, "c6g.4xlarge", "0.2000"),
+		observation(late, "m6g.4xlarge", "0.2000"),
+		observation(late, "r6g.4xlarge", "0.2000"),
+		observation(3*24*time.Hour, "r6g.4xlarge", "0.1000"), // outside the window
+		observation(0, "c6g.4xlarge", "0.3000"),              // the run's own observation
+	}
+
+	events := detectPriceEvents(data, observations, *spikeWindow, *spikePercent)
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2: %+v", len(events), events)
+	}
+	want := []PriceEvent{
+		{Kind: "spike", Region: "eu-west-1", InstanceType: "c6g.4xlarge", Before: "0.2000", BeforeTime: observations[0].Time, After: "0.3000", AfterTime: data.LastUpdated, ChangePercent: 50},
+		{Kind: "crash", Region: "eu-west-1", InstanceType: "m6g.4xlarge", Before: "0.2000", BeforeTime: observations[1].Time, After: "0.1000", AfterTime: data.LastUpdated, ChangePercent: -50},
+	}
+	for i := range want {
+		if events[i] != want[i] {
+			t.Errorf("event %d = %+v, want %+v", i, events[i], want[i])
+		}
+	}
+}
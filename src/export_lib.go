@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	jsonnetFile = flag.String("jsonnet", "", "also write the best deal per region as an importable Jsonnet library to this file")
+	cueFile     = flag.String("cue", "", "also write the best deal per region as a CUE package to this file")
+	ansibleFile = flag.String("ansible-vars", "", "also write the best deal per region as Ansible group_vars YAML to this file")
+	ansibleKey  = flag.String("ansible-prefix", "spot_", "prefix of the variables written by --ansible-vars")
+	packerFile  = flag.String("packer-vars", "", "also write the cheapest AMI build instance per architecture as a Packer var-file to this file")
+)
+
+// packerMinVCPUs is the smallest instance worth baking an AMI on
+const packerMinVCPUs = 8
+
+// LibraryDeal is a region's best instance as exposed to config-as-code consumers
+type LibraryDeal struct {
+	InstanceType        string  `json:"instanceType"`
+	VCPUS               int     `json:"vcpus"`
+	Memory              string  `json:"memory"`
+	SpotPrice           float64 `json:"spotPrice"`
+	PricePerVCPU        float64 `json:"pricePerVCPU"`
+	RecommendedMaxPrice string  `json:"recommendedMaxPrice,omitempty"`
+	InterruptionRate    string  `json:"interruptionRate,omitempty"`
+}
+
+// libraryHeader opens every generated library; both languages accept // comments
+const libraryHeader = "// Generated by ec2-spot-finder from spot_data.json. Do not edit.\n"
+
+// bestPerRegion picks each region's top instance under the region ranking
+func bestPerRegion(data SpotData) map[string]LibraryDeal {
+	best := make(map[string]LibraryDeal, len(data.Regions))
+	for region, instances := range data.Regions {
+		if len(instances) == 0 {
+			continue
+		}
+		instance := bestInstance(instances, regionStrategy)
+		price := parsePrice(instance.SpotPrice)
+		best[region] = LibraryDeal{
+			InstanceType:        instance.InstanceType,
+			VCPUS:               instance.VCPUS,
+			Memory:              instance.Memory,
+			SpotPrice:           price,
+			PricePerVCPU:        price / float64(instance.VCPUS),
+			RecommendedMaxPrice: instance.RecommendedMaxPrice,
+			InterruptionRate:    instance.InterruptionRate,
+		}
+	}
+	return best
+}
+
+// writeJsonnetLibrary writes the dataset's constants as a Jsonnet object,
+// importable with `local spot = import 'spot_data.libsonnet';`
+func writeJsonnetLibrary(filename string, data SpotData) error {
+	library := map[string]interface{}{
+		"lastUpdated": data.LastUpdated,
+		"best":        bestPerRegion(data),
+		"globalTop5":  data.GlobalTop5,
+		"attribution": data.Attribution,
+	}
+	if data.License != "" {
+		library["license"] = data.License
+	}
+	content, err := json.MarshalIndent(library, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, append([]byte(libraryHeader), append(content, '\n')...), 0644)
+}
+
+// writeCUEPackage writes the dataset's constants as the top-level fields of a
+// CUE package named spotdata
+func writeCUEPackage(filename string, data SpotData) error {
+	fields := map[string]interface{}{
+		"lastUpdated": data.LastUpdated,
+		"best":        bestPerRegion(data),
+		"globalTop5":  data.GlobalTop5,
+		"attribution": data.Attribution,
+	}
+	if data.License != "" {
+		fields["license"] = data.License
+	}
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	buf.WriteString(libraryHeader + "\npackage spotdata\n")
+	for _, name := range names {
+		value, err := json.MarshalIndent(fields[name], "", "\t")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&buf, "\n%s: %s\n", name, value)
+	}
+	return os.WriteFile(filename, buf.Bytes(), 0644)
+}
+
+// writeAnsibleVars writes the best deal per region as group_vars YAML, under
+// <prefix>best keyed by region, next to <prefix>last_updated. Strings are
+// double-quoted so values such as "<5%" survive YAML parsing.
+func writeAnsibleVars(filename, prefix string, data SpotData) error {
+	best := bestPerRegion(data)
+	regions := make([]string, 0, len(best))
+	for region := range best {
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+
+	var buf bytes.Buffer
+	buf.WriteString(strings.Replace(libraryHeader, "//", "#", 1))
+	fmt.Fprintf(&buf, "%slast_updated: %q\n", prefix, data.LastUpdated)
+	fmt.Fprintf(&buf, "%sbest:", prefix)
+	if len(regions) == 0 {
+		buf.WriteString(" {}")
+	}
+	buf.WriteString("\n")
+	for _, region := range regions {
+		deal := best[region]
+		fmt.Fprintf(&buf, "  %s:\n", region)
+		fmt.Fprintf(&buf, "    instance_type: %q\n", deal.InstanceType)
+		fmt.Fprintf(&buf, "    vcpus: %d\n", deal.VCPUS)
+		fmt.Fprintf(&buf, "    memory: %q\n", deal.Memory)
+		fmt.Fprintf(&buf, "    spot_price: %s\n", strconv.FormatFloat(deal.SpotPrice, 'f', -1, 64))
+		fmt.Fprintf(&buf, "    price_per_vcpu: %s\n", strconv.FormatFloat(deal.PricePerVCPU, 'f', -1, 64))
+		if deal.RecommendedMaxPrice != "" {
+			fmt.Fprintf(&buf, "    recommended_max_price: %q\n", deal.RecommendedMaxPrice)
+		}
+		if deal.InterruptionRate != "" {
+			fmt.Fprintf(&buf, "    interruption_rate: %q\n", deal.InterruptionRate)
+		}
+	}
+	return os.WriteFile(filename, buf.Bytes(), 0644)
+}
+
+// writePackerVars writes the cheapest instance of at least packerMinVCPUs
+// vCPUs for each architecture as a Packer HCL var-file, for AMI builds on
+// spot capacity
+func writePackerVars(filename string, data SpotData) error {
+	type build struct {
+		region   string
+		instance Instance
+		price    float64
+	}
+	cheapest := map[string]build{}
+	for region, instances := range data.Regions {
+		for _, instance := range instances {
+			price, err := strconv.ParseFloat(instance.SpotPrice, 64)
+			if err != nil || instance.VCPUS < packerMinVCPUs {
+				continue
+			}
+			arch := instanceArchitecture(instance.InstanceType)
+			current, ok := cheapest[arch]
+			if !ok || price < current.price || price == current.price && region+instance.InstanceType < current.region+current.instance.InstanceType {
+				cheapest[arch] = build{region, instance, price}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(libraryHeader)
+	for _, arch := range []string{"x86_64", "arm64"} {
+		best, ok := cheapest[arch]
+		if !ok {
+			continue
+		}
+		fmt.Fprintf(&buf, "\n%s_region        = %q\n", arch, best.region)
+		fmt.Fprintf(&buf, "%s_instance_type = %q\n", arch, best.instance.InstanceType)
+		fmt.Fprintf(&buf, "%s_spot_price    = %q\n", arch, best.instance.SpotPrice)
+	}
+	return os.WriteFile(filename, buf.Bytes(), 0644)
+}
@@ -1,10 +1,13 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
-	"io/ioutil"
 	"log"
+	"math"
 	"net/http"
 	"os"
 	"reflect"
@@ -13,20 +16,27 @@ import (
 	"strings"
 	"sync"
 	"time"
+	"unicode"
 )
 
 // Instance represents an EC2 instance type and its pricing details
 type Instance struct {
-	InstanceType   string `json:"InstanceType"`
-	VCPUS          int    `json:"VCPUS"`
-	Memory         string `json:"Memory"`
-	SpotSavingRate string `json:"SpotSavingRate"`
-	SpotPrice      string `json:"SpotPrice"`
-}
-
-// Response represents the structure of the EC2 shop API response
-type Response struct {
-	Prices []Instance `json:"Prices"`
+	InstanceType        string   `json:"InstanceType"`
+	VCPUS               int      `json:"VCPUS"`
+	Memory              string   `json:"Memory"`
+	SpotSavingRate      string   `json:"SpotSavingRate"`               // computed from SpotPrice and OnDemandPrice when both are known
+	UpstreamSavingRate  string   `json:"UpstreamSavingRate,omitempty"` // ec2.shop's rate, when it disagrees with the computed one
+	SpotPrice           string   `json:"SpotPrice"`
+	SpotPriceRange      string   `json:"SpotPriceRange,omitempty"` // set when exact prices are withheld
+	OnDemandPrice       string   `json:"OnDemandPrice,omitempty"`
+	RecommendedMaxPrice string   `json:"RecommendedMaxPrice,omitempty"` // p95 of the trailing price history
+	CheaperThan         string   `json:"CheaperThan,omitempty"`         // share of the trailing history priced higher, e.g. "85%"
+	Relaxed             bool     `json:"relaxed,omitempty"`             // admitted below the usual savings threshold
+	AtFloor             bool     `json:"at_floor,omitempty"`            // priced at its family's long-run minimum in the region
+	InterruptionRate    string   `json:"InterruptionRate,omitempty"`    // Spot Instance Advisor range, e.g. "<5%"
+	Category            string   `json:"Category,omitempty"`            // workload category shared across providers
+	Deprecated          bool     `json:"deprecated,omitempty"`          // previous-generation family
+	Credits             []string `json:"credits,omitempty"`             // configured credit programs covering the type
 }
 
 // Region represents an AWS region and its details
@@ -40,53 +50,353 @@ type Region struct {
 
 // GlobalDeal represents a spot instance deal with additional information
 type GlobalDeal struct {
-	InstanceType string  `json:"instanceType"`
-	VCPUS        int     `json:"cpus"`
-	Memory       string  `json:"memory"`
-	SpotPrice    float64 `json:"price"`
-	PricePerVCPU float64 `json:"pricePerVCPU"`
-	Region       string  `json:"region"`
+	InstanceType     string  `json:"instanceType"`
+	VCPUS            int     `json:"cpus"`
+	Memory           string  `json:"memory"`
+	SpotPrice        float64 `json:"price"`
+	PriceRange       string  `json:"priceRange,omitempty"` // set when exact prices are withheld
+	PricePerVCPU     float64 `json:"pricePerVCPU"`
+	Region           string  `json:"region"`
+	OnDemandPrice    float64 `json:"onDemandPrice,omitempty"` // worst case when falling back to on-demand
+	InterruptionRate string  `json:"interruptionRate,omitempty"`
+	// FlatRateBaseline is the cheapest comparable flat-rate server, when such providers are enabled
+	FlatRateBaseline *FlatRateBaseline `json:"flatRateBaseline,omitempty"`
+	// BaselineRatio is how many times cheaper per vCPU the deal is than the configured baseline
+	BaselineRatio float64 `json:"baselineRatio,omitempty"`
+	// BundleCost is the monthly cost with the configured storage and egress
+	BundleCost *BundleCost `json:"bundleCost,omitempty"`
+}
+
+// instance returns the deal as an Instance so ranking strategies apply to it
+func (d GlobalDeal) instance() Instance {
+	return Instance{
+		InstanceType:     d.InstanceType,
+		VCPUS:            d.VCPUS,
+		Memory:           d.Memory,
+		SpotPrice:        strconv.FormatFloat(d.SpotPrice, 'f', -1, 64),
+		OnDemandPrice:    strconv.FormatFloat(d.OnDemandPrice, 'f', -1, 64),
+		InterruptionRate: d.InterruptionRate,
+	}
 }
 
 // SpotData represents the entire dataset of spot instance deals
 type SpotData struct {
-	LastUpdated string                `json:"last_updated"`
-	Regions     map[string][]Instance `json:"regions"`
-	GlobalTop5  []GlobalDeal          `json:"global_top_5"`
+	LastUpdated      string                     `json:"last_updated"`
+	LastUpdatedLocal string                     `json:"last_updated_local,omitempty"` // in the display timezone
+	Timezone         string                     `json:"timezone,omitempty"`
+	RefreshInterval  int64                      `json:"refresh_interval_seconds,omitempty"` // expected time between refreshes
+	Regions          map[string][]Instance      `json:"regions"`
+	GlobalTop5       []GlobalDeal               `json:"global_top_5"`
+	Sources          map[string]string          `json:"sources,omitempty"`           // upstream each region was fetched from
+	RegionsUpdated   map[string]string          `json:"regions_updated,omitempty"`   // last successful fetch of each region
+	SectionsUpdated  map[string]string          `json:"sections_updated,omitempty"`  // freshness of the oldest data behind each global section
+	SavingsBuckets   map[string][]SavingsBucket `json:"savings_buckets,omitempty"`   // savings tiers for the frontend
+	Pareto           []ParetoDeal               `json:"pareto,omitempty"`            // efficient frontier across all regions
+	Providers        map[string]ProviderData    `json:"providers,omitempty"`         // deals of providers other than AWS
+	GlobalTop        []CrossProviderDeal        `json:"global_top,omitempty"`        // best deals across all providers
+	PolicyViolations []PolicyViolations         `json:"policy_violations,omitempty"` // instances excluded by the organization policy
+	License          string                     `json:"license,omitempty"`
+	Attribution      []Attribution              `json:"attribution,omitempty"` // credits of the upstream sources
 }
 
-func main() {
-	// Fetch new spot data
-	newSpotData := fetchSpotData()
+// dataFile is the published dataset read by the static site
+var dataFile = "docs/spot_data.json"
+
+// Upstream endpoints; variables so tests can point them at local mock servers
+var (
+	ec2ShopURL   = "https://ec2.shop"
+	locationsURL = "https://b0.p.awsstatic.com/locations/1.0/aws/current/locations.json"
+)
+
+var (
+	openPR         = flag.Bool("open-pr", false, "open a pull request with the updated data instead of writing it in place")
+	prBase         = flag.String("pr-base", "", "base branch for --open-pr (defaults to the repository's default branch)")
+	profile        = flag.String("profile", "", "write CPU and heap pprof profiles to <prefix>.cpu.pprof and <prefix>.heap.pprof")
+	release        = flag.Bool("release", false, "attach the snapshot (JSON, CSV and checksums) to a dated GitHub release")
+	historyFile    = flag.String("history", "docs/price_history.jsonl", "price history appended on every run; empty disables history")
+	historyWindow  = flag.Duration("history-window", 30*24*time.Hour, "trailing window of history used for recommended max prices")
+	historyCompact = flag.Int64("history-compact-size", 16<<20, "size in bytes above which observations older than the history window are moved to monthly archives")
+)
+
+// refresh fetches every provider, merges the result into the published
+// dataset, then writes and publishes everything derived from it. Errors are
+// returned for main to decide how the run fails.
+func refresh(merge func(existing, new SpotData) SpotData, inputs *recordingTransport) error {
+	if err := loadPriceVolatility(*historyFile, *historyWindow); err != nil {
+		return fmt.Errorf("reading price history: %w", err)
+	}
+	// Check the sinks before fetching rather than fail once the data is ready
+	sinks, err := configuredSinks()
+	if err != nil {
+		return fmt.Errorf("configuring sinks: %w", err)
+	}
+
+	// Fetch new spot data, withholding exact prices before anything, the
+	// quality report included, is recorded or published
+	results := fetchProviders(enabledProviders, time.Duration(config.ProviderTimeout))
+	for name, result := range results {
+		bucketSpotPrices(&result.Data, config.PriceBucketWidth)
+		results[name] = result
+	}
+	aws, ok := results["aws"]
+	if !ok {
+		return errors.New("the aws provider must be enabled to update the published data")
+	}
+	if *qualityFile != "" {
+		if err := writeQualityReport(*qualityFile, aws.Data, aws.Err); err != nil {
+			return fmt.Errorf("writing quality report: %w", err)
+		}
+	}
+	if aws.Err != nil {
+		return fmt.Errorf("fetching spot data from aws: %w", aws.Err)
+	}
+	if len(aws.Data.Regions) == 0 {
+		return fmt.Errorf("%w: no region could be fetched from aws", ErrUpstreamUnavailable)
+	}
+	newSpotData := aws.Data
+	for name, result := range results {
+		if name == "aws" || result.Err != nil {
+			continue
+		}
+		if newSpotData.Providers == nil {
+			newSpotData.Providers = make(map[string]ProviderData)
+		}
+		newSpotData.Providers[name] = ProviderData{Regions: result.Data.Regions, Sources: result.Data.Sources, LastUpdated: result.Data.LastUpdated}
+	}
+
+	// Record the fresh prices and derive max-price recommendations from the trailing window
+	var events []PriceEvent
+	if *historyFile != "" {
+		if events, err = recordHistory(*historyFile, &newSpotData); err != nil {
+			return err
+		}
+	}
 
 	// Read existing data if file exists
-	existingData, err := readExistingData("docs/spot_data.json")
+	var diff SpotDiff
+	existingData, err := readExistingData(dataFile)
 	if err == nil {
-		// Merge new data with existing data, preserving order
-		mergedData := mergeSpotData(existingData, newSpotData)
+		// Combine new data with existing data as the merge mode says
+		mergedData := merge(existingData, newSpotData)
+		// Merging may keep instances the fresh data no longer lists; append
+		// promises never to drop published ones, so only caps fresh data
+		if *mergeMode != "append" {
+			capRegions(&mergedData)
+		}
 
 		if reflect.DeepEqual(existingData, mergedData) {
 			log.Println("No changes in spot data. Skipping file write.")
-			return
+			if err := recordRun(existingData.LastUpdated, false); err != nil {
+				return fmt.Errorf("recording run: %w", err)
+			}
+			return nil
 		}
 
+		diff = diffSpotData(existingData, mergedData)
 		newSpotData = mergedData
+	} else {
+		diff = diffSpotData(SpotData{}, newSpotData)
+	}
+
+	deriveSections(&newSpotData)
+
+	// Write merged data to file unless it goes through review instead
+	if !*openPR {
+		if err := writeDataFile(dataFile, newSpotData); err != nil {
+			return fmt.Errorf("writing spot data: %w", err)
+		}
+		if err := writeMinifiedDataFile(dataFile, newSpotData); err != nil {
+			return fmt.Errorf("writing minified spot data: %w", err)
+		}
+		log.Println("Updated spot data written to file.")
+
+		if inputs != nil {
+			if err := writeProvenance(*provenanceFile, dataFile, inputs); err != nil {
+				return fmt.Errorf("writing provenance: %w", err)
+			}
+		}
+	}
+
+	// The files the site reads next to the dataset are written either way;
+	// in GitOps mode they go into the pull request with it
+	if err := recordRun(newSpotData.LastUpdated, true); err != nil {
+		return fmt.Errorf("recording run: %w", err)
+	}
+	if *diffFile != "" {
+		if err := writeDiffFile(*diffFile, existingData, newSpotData, diff); err != nil {
+			return fmt.Errorf("writing diff file: %w", err)
+		}
+	}
+
+	// Export constants for config-as-code consumers
+	if *jsonnetFile != "" {
+		if err := writeJsonnetLibrary(*jsonnetFile, newSpotData); err != nil {
+			return fmt.Errorf("writing Jsonnet library: %w", err)
+		}
+	}
+	if *cueFile != "" {
+		if err := writeCUEPackage(*cueFile, newSpotData); err != nil {
+			return fmt.Errorf("writing CUE package: %w", err)
+		}
+	}
+	if *ansibleFile != "" {
+		if err := writeAnsibleVars(*ansibleFile, *ansibleKey, newSpotData); err != nil {
+			return fmt.Errorf("writing Ansible variables: %w", err)
+		}
+	}
+	if *packerFile != "" {
+		if err := writePackerVars(*packerFile, newSpotData); err != nil {
+			return fmt.Errorf("writing Packer variables: %w", err)
+		}
+	}
+	if *nodeLabelsFile != "" {
+		if err := writeNodeLabels(*nodeLabelsFile, newSpotData); err != nil {
+			return fmt.Errorf("writing node labels: %w", err)
+		}
+	}
+	if *openCostFile != "" {
+		if err := writeOpenCostCSV(*openCostFile, newSpotData); err != nil {
+			return fmt.Errorf("writing OpenCost pricing: %w", err)
+		}
+	}
+	if *heatmapFile != "" {
+		if err := writeHeatmap(*heatmapFile, newSpotData); err != nil {
+			return fmt.Errorf("writing heat map: %w", err)
+		}
+	}
+	if *leaderboardFile != "" {
+		if err := updateLeaderboard(*leaderboardFile, *leaderboardHistory, newSpotData); err != nil {
+			return fmt.Errorf("updating leaderboard: %w", err)
+		}
+	}
+	if *eventsFile != "" {
+		if err := updateEventLog(*eventsFile, events, newSpotData.LastUpdated); err != nil {
+			return fmt.Errorf("updating event log: %w", err)
+		}
 	}
 
-	// Write merged data to file
-	file, err := os.Create("docs/spot_data.json")
+	// Write each team's top list
+	if err := writeProfiles(config.ProfilesDir, config.Profiles, newSpotData); err != nil {
+		return fmt.Errorf("writing profiles: %w", err)
+	}
+
+	// Render user-defined artifacts such as chat messages or wiki tables
+	if err := renderTemplates(templateFiles, newSpotData); err != nil {
+		return fmt.Errorf("rendering templates: %w", err)
+	}
+
+	// Only keep an in-memory copy of the encoded dataset when something publishes it
+	if !*release && !*openPR && len(sinks) == 0 {
+		return nil
+	}
+	content, err := encodeSpotData(newSpotData)
 	if err != nil {
-		log.Fatal(err)
+		return fmt.Errorf("encoding spot data: %w", err)
+	}
+
+	// Attach the snapshot to the day's release for stable download URLs
+	if *release {
+		releaseURL, err := publishRelease(newSpotData, content)
+		if err != nil {
+			return fmt.Errorf("%w: release: %w", ErrPublish, err)
+		}
+		log.Printf("Published snapshot to release: %s", releaseURL)
 	}
-	defer file.Close()
 
-	encoder := json.NewEncoder(file)
-	encoder.SetIndent("", "  ")
-	if err := encoder.Encode(newSpotData); err != nil {
-		log.Fatal(err)
+	// In GitOps mode the change goes through review instead of being written in place
+	if *openPR {
+		files, err := pullRequestFiles(newSpotData, content)
+		if err != nil {
+			return fmt.Errorf("listing pull request files: %w", err)
+		}
+		prURL, err := openDataPullRequest(files, diff, *prBase)
+		if err != nil {
+			return fmt.Errorf("%w: opening pull request: %w", ErrPublish, err)
+		}
+		log.Printf("Opened pull request with updated spot data: %s", prURL)
+		return nil
 	}
 
-	log.Println("Updated spot data written to file.")
+	// Mirror the snapshot to any configured external stores
+	if len(sinks) > 0 {
+		if err := publishSnapshot(sinks, Snapshot{Data: newSpotData, Diff: diff, JSON: content}); err != nil {
+			return fmt.Errorf("%w: %w", ErrPublish, err)
+		}
+	}
+	return nil
+}
+
+// deriveSections enforces the organization policy on the merged regions and
+// computes the published sections derived from them
+func deriveSections(data *SpotData) {
+	enforcePolicy(data)
+	data.SavingsBuckets = savingsBuckets(data.Regions, config.SavingsBuckets)
+	data.Pareto = paretoFrontier(data.Regions)
+	data.GlobalTop = crossProviderTop(*data)
+	data.License = config.License
+	data.LastUpdatedLocal, data.Timezone = localTimestamp(data.LastUpdated)
+	data.RefreshInterval = int64(time.Duration(config.RefreshInterval).Seconds())
+	data.SectionsUpdated = sectionTimestamps(*data)
+	data.Attribution = datasetAttributions(*data)
+	data.GlobalTop5 = flatRateBaselines(data.GlobalTop5, data.Providers)
+	for i := range data.GlobalTop5 {
+		data.GlobalTop5[i].BaselineRatio = baselineRatio(data.GlobalTop5[i].PricePerVCPU)
+		data.GlobalTop5[i].BundleCost = bundleCost(data.GlobalTop5[i].Region, data.GlobalTop5[i].SpotPrice)
+	}
+	for i := range data.GlobalTop {
+		data.GlobalTop[i].BaselineRatio = baselineRatio(data.GlobalTop[i].PricePerVCPU)
+	}
+}
+
+// sectionTimestamps dates each global section by the oldest data it was
+// derived from: the top 5 only ranks regions fetched in this run, while
+// the other sections also cover regions kept from earlier runs
+func sectionTimestamps(data SpotData) map[string]string {
+	regions := data.LastUpdated
+	for region := range data.Regions {
+		if updated, ok := data.RegionsUpdated[region]; ok && updated < regions {
+			regions = updated
+		}
+	}
+	providers := regions
+	for _, provider := range data.Providers {
+		if provider.LastUpdated != "" && provider.LastUpdated < providers {
+			providers = provider.LastUpdated
+		}
+	}
+
+	sections := map[string]string{"global_top_5": data.LastUpdated, "savings_buckets": regions, "pareto": regions}
+	if len(data.GlobalTop) > 0 {
+		sections["global_top"] = providers
+	}
+	return sections
+}
+
+// localTimestamp renders an RFC 3339 UTC timestamp in the display timezone
+func localTimestamp(utc string) (string, string) {
+	t, err := time.Parse(time.RFC3339, utc)
+	if err != nil {
+		return "", ""
+	}
+	return t.In(displayLocation).Format(time.RFC3339), displayLocation.String()
+}
+
+// baselineRatio compares a price per vCPU to the configured baseline, e.g.
+// 4.5 for a deal 4.5 times cheaper; zero when no baseline is configured
+func baselineRatio(pricePerVCPU float64) float64 {
+	if config.BaselinePricePerVCPU <= 0 || pricePerVCPU <= 0 || math.IsInf(pricePerVCPU, 1) {
+		return 0
+	}
+	return math.Round(config.BaselinePricePerVCPU/pricePerVCPU*100) / 100
+}
+
+// encodeSpotData renders the dataset as indented JSON
+func encodeSpotData(data SpotData) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := writeSpotData(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
 }
 
 func readExistingData(filename string) (SpotData, error) {
@@ -105,6 +415,12 @@ func readExistingData(filename string) (SpotData, error) {
 func mergeSpotData(existing, new SpotData) SpotData {
 	merged := existing
 
+	// Copy the regions so the caller's dataset is left untouched
+	merged.Regions = make(map[string][]Instance, len(existing.Regions))
+	for region, instances := range existing.Regions {
+		merged.Regions[region] = instances
+	}
+
 	// Update LastUpdated if changed
 	if existing.LastUpdated != new.LastUpdated {
 		merged.LastUpdated = new.LastUpdated
@@ -119,6 +435,12 @@ func mergeSpotData(existing, new SpotData) SpotData {
 		}
 	}
 
+	// Track where each refreshed region's data came from and when
+	merged.Sources = mergeRegionLabels(existing.Sources, new.Sources)
+	merged.RegionsUpdated = mergeRegionLabels(existing.RegionsUpdated, new.RegionsUpdated)
+
+	merged.Providers = mergeProviders(existing.Providers, new.Providers)
+
 	// Update GlobalTop5 if changed
 	if !reflect.DeepEqual(existing.GlobalTop5, new.GlobalTop5) {
 		merged.GlobalTop5 = new.GlobalTop5
@@ -127,21 +449,46 @@ func mergeSpotData(existing, new SpotData) SpotData {
 	return merged
 }
 
+// mergeRegionLabels overlays the per-region values of a refresh on the
+// existing ones, keeping those of regions it did not fetch
+func mergeRegionLabels(existing, new map[string]string) map[string]string {
+	if len(new) == 0 {
+		return existing
+	}
+	merged := make(map[string]string, len(existing)+len(new))
+	for region, value := range existing {
+		merged[region] = value
+	}
+	for region, value := range new {
+		merged[region] = value
+	}
+	return merged
+}
+
 func mergeInstances(existing, new []Instance) []Instance {
-	merged := make([]Instance, len(existing))
-	copy(merged, existing)
+	merged := make([]Instance, 0, len(existing))
 
+	// Canonicalize existing entries, folding duplicates left by earlier
+	// formatting differences into their first occurrence
 	existingMap := make(map[string]int)
-	for i, instance := range existing {
-		existingMap[instance.InstanceType] = i
+	for _, instance := range existing {
+		instance = canonicalInstance(instance)
+		if i, ok := existingMap[instance.InstanceType]; ok {
+			merged[i] = instance
+			continue
+		}
+		existingMap[instance.InstanceType] = len(merged)
+		merged = append(merged, instance)
 	}
 
 	for _, newInstance := range new {
+		newInstance = canonicalInstance(newInstance)
 		if i, ok := existingMap[newInstance.InstanceType]; ok {
 			// Update existing instance
 			merged[i] = newInstance
 		} else {
 			// Add new instance
+			existingMap[newInstance.InstanceType] = len(merged)
 			merged = append(merged, newInstance)
 		}
 	}
@@ -149,45 +496,86 @@ func mergeInstances(existing, new []Instance) []Instance {
 	return merged
 }
 
+// canonicalInstance normalizes cosmetic upstream formatting so the same
+// instance merges onto itself: surrounding spaces are trimmed, memory reads
+// like "32 GiB" and savings rates like "67%"
+func canonicalInstance(instance Instance) Instance {
+	instance.InstanceType = strings.ToLower(strings.TrimSpace(instance.InstanceType))
+	instance.SpotPrice = strings.TrimSpace(instance.SpotPrice)
+
+	memory := strings.Join(strings.Fields(instance.Memory), " ")
+	if i := strings.IndexFunc(memory, unicode.IsLetter); i > 0 && memory[i-1] != ' ' {
+		memory = memory[:i] + " " + memory[i:]
+	}
+	instance.Memory = memory
+
+	rate := strings.Join(strings.Fields(instance.SpotSavingRate), "")
+	if rate != "" && !strings.HasSuffix(rate, "%") {
+		if _, err := strconv.ParseFloat(rate, 64); err == nil {
+			rate += "%"
+		}
+	}
+	instance.SpotSavingRate = rate
+
+	return instance
+}
+
 // fetchSpotData retrieves spot instance data for all regions
-func fetchSpotData() SpotData {
+func fetchSpotData() (SpotData, error) {
 	regions, err := fetchRegions()
 	if err != nil {
-		log.Fatalf("Error fetching regions: %v", err)
+		return SpotData{}, err
 	}
 
 	var wg sync.WaitGroup
 	spotData := SpotData{
-		LastUpdated: time.Now().UTC().Format(time.RFC3339),
-		Regions:     make(map[string][]Instance),
+		LastUpdated:    clock().UTC().Format(time.RFC3339),
+		Regions:        make(map[string][]Instance),
+		Sources:        make(map[string]string),
+		RegionsUpdated: make(map[string]string),
 	}
 	var globalDeals []GlobalDeal
+	var emptyRegions []string
+	var regionErrs []error
+	outcome := FetchOutcome{Failed: make(map[string]error)}
 	var mu sync.Mutex
 
 	// Fetch spot deals for each region concurrently
 	for _, region := range regions {
+		if policy.ForbidsRegion(region) {
+			continue
+		}
+		outcome.Expected++
 		wg.Add(1)
 		go func(r string) {
 			defer wg.Done()
-			deals, err := getSpotDeals(r)
-			if err != nil {
-				log.Printf("Error getting spot deals for region %s: %v", r, err)
-				return
+			// Regions reused from the checkpoint are dated by when they were
+			// fetched rather than by this run
+			updated := spotData.LastUpdated
+			deals, fetched, ok := checkpoint.Lookup(r)
+			if ok {
+				updated = fetched.UTC().Format(time.RFC3339)
+			} else {
+				var err error
+				if deals, err = getSpotDeals(r); err != nil {
+					log.Printf("Error getting spot deals for region %s: %v", r, err)
+					mu.Lock()
+					regionErrs = append(regionErrs, fmt.Errorf("%s: %w", r, err))
+					outcome.Failed[r] = err
+					mu.Unlock()
+					return
+				}
+				checkpoint.Save(r, deals)
 			}
 			mu.Lock()
 			if len(deals) > 0 {
 				spotData.Regions[r] = deals
-				// Add the best deal from this region to globalDeals
-				price, _ := strconv.ParseFloat(deals[0].SpotPrice, 64)
-				pricePerVCPU := price / float64(deals[0].VCPUS)
-				globalDeals = append(globalDeals, GlobalDeal{
-					InstanceType: deals[0].InstanceType,
-					VCPUS:        deals[0].VCPUS,
-					Memory:       deals[0].Memory,
-					SpotPrice:    price,
-					PricePerVCPU: pricePerVCPU,
-					Region:       r,
-				})
+				spotData.Sources[r] = sourceEC2Shop
+				spotData.RegionsUpdated[r] = updated
+				// Add the best deals from this region to globalDeals
+				globalDeals = append(globalDeals, regionDeals(r, deals)...)
+			} else {
+				emptyRegions = append(emptyRegions, r)
 			}
 			mu.Unlock()
 		}(region)
@@ -195,98 +583,157 @@ func fetchSpotData() SpotData {
 
 	wg.Wait()
 
-	// Sort global deals by price per vCPU
-	sort.Slice(globalDeals, func(i, j int) bool {
-		return globalDeals[i].PricePerVCPU < globalDeals[j].PricePerVCPU
-	})
+	// Regions finish in any order; sort them so the output is the same every run
+	sort.Strings(emptyRegions)
 
-	// Select top 5 global deals
-	if len(globalDeals) > 5 {
-		spotData.GlobalTop5 = globalDeals[:5]
-	} else {
-		spotData.GlobalTop5 = globalDeals
+	// ec2.shop can lag behind newly launched regions, so fill known regions
+	// it returned nothing for from AWS's own spot price feed
+	if len(emptyRegions) > 0 {
+		fallback, err := awsSpotFallback(emptyRegions, spotData)
+		if err != nil {
+			log.Printf("Error fetching AWS spot price feed: %v", err)
+		}
+		for r, deals := range fallback {
+			log.Printf("ec2.shop returned no deals for region %s, using the AWS spot price feed", r)
+			spotData.Regions[r] = deals
+			spotData.Sources[r] = sourceAWSSpotFeed
+			spotData.RegionsUpdated[r] = spotData.LastUpdated
+			globalDeals = append(globalDeals, regionDeals(r, deals)...)
+			outcome.Fallback = append(outcome.Fallback, r)
+		}
+		sort.Strings(outcome.Fallback)
+	}
+	recordFetchOutcome(outcome)
+
+	// Without a single region, report why the regions failed
+	if len(spotData.Regions) == 0 && len(regionErrs) > 0 {
+		return SpotData{}, fmt.Errorf("no region could be fetched: %w", errors.Join(regionErrs...))
 	}
 
-	return spotData
+	sort.Slice(globalDeals, func(i, j int) bool { return globalDeals[i].Region < globalDeals[j].Region })
+	spotData.GlobalTop5 = topGlobalDeals(globalDeals)
+
+	return spotData, nil
 }
 
-// getSpotDeals fetches spot deals for a specific region
-func getSpotDeals(region string) ([]Instance, error) {
-	url := fmt.Sprintf("https://ec2.shop?region=%s&filter=ebs,cpu>=4,cpu<=32", region)
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
+// topGlobalDeals ranks the regions' best deals with the global strategy and
+// keeps the top 5, within the family and region limits of the config
+func topGlobalDeals(deals []GlobalDeal) []GlobalDeal {
+	sort.SliceStable(deals, func(i, j int) bool {
+		return globalStrategy.Less(deals[i].instance(), deals[j].instance())
+	})
+	return diverseTop(deals, 5, config.TopMaxPerFamily, config.TopMaxPerRegion)
+}
+
+// bestInstance returns the top instance of a non-empty list under strategy
+func bestInstance(instances []Instance, strategy Strategy) Instance {
+	best := instances[0]
+	for _, instance := range instances[1:] {
+		if strategy.Less(instance, best) {
+			best = instance
+		}
 	}
-	req.Header.Set("accept", "json")
+	return best
+}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
+// bestDeal describes the region's top instance under the global strategy,
+// which may rank instances differently from the region's own list
+func bestDeal(region string, deals []Instance) GlobalDeal {
+	return newGlobalDeal(region, bestInstance(deals, globalStrategy))
+}
+
+// newGlobalDeal describes an instance of a region as a global deal
+func newGlobalDeal(region string, best Instance) GlobalDeal {
+	price, _ := strconv.ParseFloat(best.SpotPrice, 64)
+	onDemandPrice, _ := strconv.ParseFloat(best.OnDemandPrice, 64)
+	return GlobalDeal{
+		InstanceType:     best.InstanceType,
+		VCPUS:            best.VCPUS,
+		Memory:           best.Memory,
+		SpotPrice:        price,
+		PricePerVCPU:     price / float64(best.VCPUS),
+		Region:           region,
+		OnDemandPrice:    onDemandPrice,
+		InterruptionRate: best.InterruptionRate,
 	}
-	defer resp.Body.Close()
+}
 
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
+// Savings-rate filter applied to regional deals
+const (
+	savingsThreshold = 50 // percent an instance must save over on-demand
+	relaxStep        = 10 // percentage points the threshold drops per relaxation
+)
+
+// selectBySavings keeps instances saving more than savingsThreshold. When
+// fewer than min qualify, the threshold is lowered step by step and
+// instances admitted below the usual threshold are marked as relaxed.
+func selectBySavings(instances []Instance, min int) []Instance {
+	for threshold := savingsThreshold; ; threshold -= relaxStep {
+		var selected []Instance
+		for _, instance := range instances {
+			savingsRate, err := strconv.Atoi(strings.TrimSuffix(instance.SpotSavingRate, "%"))
+			if err != nil || savingsRate <= threshold {
+				continue
+			}
+			instance.Relaxed = savingsRate <= savingsThreshold
+			selected = append(selected, instance)
+		}
+		if len(selected) >= min || threshold <= 0 {
+			return selected
+		}
 	}
+}
 
-	var response Response
-	err = json.Unmarshal(body, &response)
+// getSpotDeals fetches spot deals for a specific region
+func getSpotDeals(region string) ([]Instance, error) {
+	// Keep instances permitted by the allow/deny list while decoding
+	permitted, err := fetchRegionPrices(region, "ebs,cpu>=4,cpu<=32", func(instance Instance) bool {
+		return instanceFilter.Permits(instance.InstanceType)
+	})
 	if err != nil {
 		return nil, err
 	}
 
 	// Filter instances with high savings rate (>50%)
-	var highSavingsInstances []Instance
-	for _, instance := range response.Prices {
-		savingsRate, err := strconv.Atoi(strings.TrimSuffix(instance.SpotSavingRate, "%"))
-		if err == nil && savingsRate > 50 {
-			highSavingsInstances = append(highSavingsInstances, instance)
-		}
-	}
+	highSavingsInstances := selectBySavings(permitted, config.MinInstancesPerRegion)
 
-	// Sort instances by price per vCPU
-	sort.Slice(highSavingsInstances, func(i, j int) bool {
-		priceI, _ := strconv.ParseFloat(highSavingsInstances[i].SpotPrice, 64)
-		priceJ, _ := strconv.ParseFloat(highSavingsInstances[j].SpotPrice, 64)
-		ratioI := priceI / float64(highSavingsInstances[i].VCPUS)
-		ratioJ := priceJ / float64(highSavingsInstances[j].VCPUS)
-		return ratioI < ratioJ
-	})
+	// Rank instances with the configured strategy (price per vCPU by default)
+	applyInterruptionRates(region, highSavingsInstances)
+	applyCategories("aws", highSavingsInstances)
+	highSavingsInstances = applyDeprecations(highSavingsInstances)
+	applyCredits(highSavingsInstances)
+	highSavingsInstances = policy.Filter(region, highSavingsInstances)
+	rankInstances(highSavingsInstances, regionStrategy)
 
-	return highSavingsInstances, nil
+	return capInstances(highSavingsInstances, *maxPerRegion, regionStrategy), nil
 }
 
-// fetchRegions retrieves the list of AWS regions
-func fetchRegions() ([]string, error) {
-	resp, err := http.Get("https://b0.p.awsstatic.com/locations/1.0/aws/current/locations.json")
-	if err != nil {
-		return nil, err
+// fetchRegionPrices lists a region's instances matching an ec2.shop filter,
+// keeping those accepted by keep
+func fetchRegionPrices(region, filter string, keep func(Instance) bool) ([]Instance, error) {
+	url := fmt.Sprintf("%s?region=%s", ec2ShopURL, region)
+	if filter != "" {
+		url += "&filter=" + filter
 	}
-	defer resp.Body.Close()
-
-	body, err := ioutil.ReadAll(resp.Body)
+	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
+	req.Header.Set("accept", "json")
 
-	var regions map[string]Region
-	err = json.Unmarshal(body, &regions)
+	client := &http.Client{}
+	resp, err := client.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %w", ErrUpstreamUnavailable, err)
 	}
-
-	// Extract region codes for AWS Regions
-	var regionCodes []string
-	for _, region := range regions {
-		if region.Type == "AWS Region" {
-			regionCodes = append(regionCodes, region.Code)
-		}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: ec2.shop: unexpected status %s", ErrUpstreamUnavailable, resp.Status)
 	}
 
-	// Sort region codes alphabetically
-	sort.Strings(regionCodes)
-
-	return regionCodes, nil
+	instances, err := decodePrices(newLimitedReader(resp.Body, maxResponseBytes), keep)
+	if err != nil && !errors.Is(err, ErrValidation) {
+		return nil, fmt.Errorf("%w: %w", ErrDecode, err)
+	}
+	return instances, err
 }
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Flat-rate provider APIs; variables so tests can point them at local mock servers
+var (
+	hetznerServerTypesURL = "https://api.hetzner.cloud/v1/server_types?per_page=50"
+	digitalOceanSizesURL  = "https://api.digitalocean.com/v2/sizes?per_page=200"
+)
+
+// flatRateProviders are billed at fixed hourly prices without interruptions.
+// They are published as a cost baseline: when they approach the spot price,
+// spot is no longer worth its complexity.
+var flatRateProviders = map[string]bool{"hetzner": true, "digitalocean": true}
+
+func init() {
+	providers["hetzner"] = hetznerProvider{}
+	providers["digitalocean"] = digitalOceanProvider{}
+}
+
+// FlatRateBaseline is the cheapest flat-rate server at least as large as a deal
+type FlatRateBaseline struct {
+	Provider     string  `json:"provider"`
+	Region       string  `json:"region"`
+	InstanceType string  `json:"instanceType"`
+	Price        float64 `json:"price"`
+	PricePerVCPU float64 `json:"pricePerVCPU"`
+}
+
+// flatRateBaselines attaches to each top deal the cheapest flat-rate server
+// with at least as many vCPUs and as much memory
+func flatRateBaselines(deals []GlobalDeal, providers map[string]ProviderData) []GlobalDeal {
+	if len(deals) == 0 {
+		return deals
+	}
+	withBaselines := make([]GlobalDeal, len(deals))
+	for i, deal := range deals {
+		deal.FlatRateBaseline = nil
+		for provider, data := range providers {
+			if !flatRateProviders[provider] {
+				continue
+			}
+			for region, instances := range data.Regions {
+				for _, instance := range instances {
+					price := parsePrice(instance.SpotPrice)
+					if instance.VCPUS < deal.VCPUS || parseLeadingNumber(instance.Memory) < parseLeadingNumber(deal.Memory) || price == 0 {
+						continue
+					}
+					if deal.FlatRateBaseline == nil || price < deal.FlatRateBaseline.Price {
+						deal.FlatRateBaseline = &FlatRateBaseline{provider, region, instance.InstanceType, price, price / float64(instance.VCPUS)}
+					}
+				}
+			}
+		}
+		withBaselines[i] = deal
+	}
+	return withBaselines
+}
+
+// flatRateInstance describes a flat-rate server size, whose fixed hourly
+// price is published in the SpotPrice field for comparison
+func flatRateInstance(name string, vcpus int, memoryGiB, hourly float64) Instance {
+	return Instance{
+		InstanceType: name,
+		VCPUS:        vcpus,
+		Memory:       strconv.FormatFloat(memoryGiB, 'f', -1, 64) + " GiB",
+		SpotPrice:    strconv.FormatFloat(hourly, 'f', 4, 64),
+	}
+}
+
+// getProviderJSON fetches an authenticated provider API into v
+func getProviderJSON(ctx context.Context, url, token string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrUpstreamUnavailable, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: %s: unexpected status %s", ErrUpstreamUnavailable, req.URL.Host, resp.Status)
+	}
+	if err := json.NewDecoder(newLimitedReader(resp.Body, maxResponseBytes)).Decode(v); err != nil {
+		return fmt.Errorf("%w: %w", ErrDecode, err)
+	}
+	return nil
+}
+
+// hetznerProvider lists Hetzner Cloud server types, read with HCLOUD_TOKEN.
+// Prices are converted from euros with the eur_usd_rate config setting.
+type hetznerProvider struct{}
+
+func (hetznerProvider) Name() string {
+	return "hetzner"
+}
+
+func (hetznerProvider) Fetch(ctx context.Context) (SpotData, error) {
+	token := os.Getenv("HCLOUD_TOKEN")
+	if token == "" {
+		return SpotData{}, errors.New("HCLOUD_TOKEN is not set")
+	}
+
+	var response struct {
+		ServerTypes []struct {
+			Name       string  `json:"name"`
+			Cores      int     `json:"cores"`
+			Memory     float64 `json:"memory"`
+			Deprecated bool    `json:"deprecated"`
+			Prices     []struct {
+				Location    string `json:"location"`
+				PriceHourly struct {
+					Net string `json:"net"`
+				} `json:"price_hourly"`
+			} `json:"prices"`
+		} `json:"server_types"`
+	}
+	if err := getProviderJSON(ctx, hetznerServerTypesURL, token, &response); err != nil {
+		return SpotData{}, err
+	}
+
+	data := SpotData{LastUpdated: clock().UTC().Format(time.RFC3339), Regions: make(map[string][]Instance), Sources: make(map[string]string)}
+	for _, serverType := range response.ServerTypes {
+		if serverType.Deprecated {
+			continue
+		}
+		for _, price := range serverType.Prices {
+			euros, err := strconv.ParseFloat(price.PriceHourly.Net, 64)
+			if err != nil {
+				continue
+			}
+			instance := flatRateInstance(serverType.Name, serverType.Cores, serverType.Memory, euros*config.EURUSDRate)
+			data.Regions[price.Location] = append(data.Regions[price.Location], instance)
+			data.Sources[price.Location] = sourceHetzner
+		}
+	}
+	for _, instances := range data.Regions {
+		applyCategories("hetzner", instances)
+	}
+	return data, nil
+}
+
+// digitalOceanProvider lists DigitalOcean Droplet sizes, read with DIGITALOCEAN_TOKEN
+type digitalOceanProvider struct{}
+
+func (digitalOceanProvider) Name() string {
+	return "digitalocean"
+}
+
+func (digitalOceanProvider) Fetch(ctx context.Context) (SpotData, error) {
+	token := os.Getenv("DIGITALOCEAN_TOKEN")
+	if token == "" {
+		return SpotData{}, errors.New("DIGITALOCEAN_TOKEN is not set")
+	}
+
+	var response struct {
+		Sizes []struct {
+			Slug        string   `json:"slug"`
+			Memory      float64  `json:"memory"` // MiB
+			VCPUS       int      `json:"vcpus"`
+			PriceHourly float64  `json:"price_hourly"`
+			Regions     []string `json:"regions"`
+			Available   bool     `json:"available"`
+		} `json:"sizes"`
+	}
+	if err := getProviderJSON(ctx, digitalOceanSizesURL, token, &response); err != nil {
+		return SpotData{}, err
+	}
+
+	data := SpotData{LastUpdated: clock().UTC().Format(time.RFC3339), Regions: make(map[string][]Instance), Sources: make(map[string]string)}
+	for _, size := range response.Sizes {
+		if !size.Available {
+			continue
+		}
+		instance := flatRateInstance(size.Slug, size.VCPUS, size.Memory/1024, size.PriceHourly)
+		for _, region := range size.Regions {
+			data.Regions[region] = append(data.Regions[region], instance)
+			data.Sources[region] = sourceDigitalOcean
+		}
+	}
+	for _, instances := range data.Regions {
+		applyCategories("digitalocean", instances)
+	}
+	return data, nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+var (
+	comboFormat         = comboFlags.String("format", "text", "output format: text, spot-fleet (RequestSpotFleet config), create-fleet (CreateFleet input), crossplane or ack (EKS node group manifests), ecs (Auto Scaling group input) or nomad (autoscaler policy)")
+	comboAMI            = comboFlags.String("ami", "<AMI_ID>", "AMI for fleet requests; left as a placeholder by default")
+	comboSubnets        = comboFlags.String("subnets", "<SUBNET_ID>", "comma-separated subnets for fleet requests, one per Availability Zone to spread across")
+	comboFleetRole      = comboFlags.String("iam-fleet-role", "<IAM_FLEET_ROLE_ARN>", "IAM role granting Spot Fleet permission to launch instances")
+	comboLaunchTemplate = comboFlags.String("launch-template", "<LAUNCH_TEMPLATE_NAME>", "launch template CreateFleet requests build on")
+)
+
+// SpotFleetRequest is the SpotFleetRequestConfig accepted by
+// aws ec2 request-spot-fleet --spot-fleet-request-config
+type SpotFleetRequest struct {
+	AllocationStrategy   string                         `json:"AllocationStrategy"`
+	IamFleetRole         string                         `json:"IamFleetRole"`
+	TargetCapacity       int                            `json:"TargetCapacity"`
+	Type                 string                         `json:"Type"`
+	LaunchSpecifications []SpotFleetLaunchSpecification `json:"LaunchSpecifications"`
+}
+
+// SpotFleetLaunchSpecification launches one instance type into one subnet,
+// each instance counting as WeightedCapacity vCPUs towards the target
+type SpotFleetLaunchSpecification struct {
+	ImageId          string  `json:"ImageId"`
+	InstanceType     string  `json:"InstanceType"`
+	SubnetId         string  `json:"SubnetId"`
+	WeightedCapacity float64 `json:"WeightedCapacity"`
+}
+
+// CreateFleetRequest is the input accepted by aws ec2 create-fleet --cli-input-json
+type CreateFleetRequest struct {
+	Type                        string                      `json:"Type"`
+	SpotOptions                 map[string]string           `json:"SpotOptions"`
+	TargetCapacitySpecification map[string]interface{}      `json:"TargetCapacitySpecification"`
+	LaunchTemplateConfigs       []FleetLaunchTemplateConfig `json:"LaunchTemplateConfigs"`
+}
+
+// FleetLaunchTemplateConfig overrides a launch template per instance type and subnet
+type FleetLaunchTemplateConfig struct {
+	LaunchTemplateSpecification map[string]string `json:"LaunchTemplateSpecification"`
+	Overrides                   []FleetOverride   `json:"Overrides"`
+}
+
+// FleetOverride is one instance type and subnet a CreateFleet request may launch
+type FleetOverride struct {
+	ImageId          string  `json:"ImageId"`
+	InstanceType     string  `json:"InstanceType"`
+	SubnetId         string  `json:"SubnetId"`
+	WeightedCapacity float64 `json:"WeightedCapacity"`
+}
+
+// fleetSubnets splits the --subnets flag
+func fleetSubnets() []string {
+	var subnets []string
+	for _, subnet := range strings.Split(*comboSubnets, ",") {
+		if subnet = strings.TrimSpace(subnet); subnet != "" {
+			subnets = append(subnets, subnet)
+		}
+	}
+	return subnets
+}
+
+// spotFleetConfig renders a combo as a Spot Fleet request maintaining its
+// vCPU count, weighting every instance type by its vCPUs. The
+// price-capacity-optimized strategy lets the fleet lean towards deeper pools
+// rather than strictly follow the combo's counts.
+func spotFleetConfig(combo Combo) ([]byte, error) {
+	request := SpotFleetRequest{
+		AllocationStrategy: "priceCapacityOptimized",
+		IamFleetRole:       *comboFleetRole,
+		TargetCapacity:     combo.VCPUS,
+		Type:               "maintain",
+	}
+	for _, pick := range combo.Picks {
+		for _, subnet := range fleetSubnets() {
+			request.LaunchSpecifications = append(request.LaunchSpecifications, SpotFleetLaunchSpecification{
+				ImageId:          *comboAMI,
+				InstanceType:     pick.Instance.InstanceType,
+				SubnetId:         subnet,
+				WeightedCapacity: float64(pick.Instance.VCPUS),
+			})
+		}
+	}
+	return marshalFleetRequest(request)
+}
+
+// createFleetConfig renders a combo as a CreateFleet request maintaining its
+// vCPU count on spot capacity
+func createFleetConfig(combo Combo) ([]byte, error) {
+	config := FleetLaunchTemplateConfig{
+		LaunchTemplateSpecification: map[string]string{
+			"LaunchTemplateName": *comboLaunchTemplate,
+			"Version":            "$Latest",
+		},
+	}
+	for _, pick := range combo.Picks {
+		for _, subnet := range fleetSubnets() {
+			config.Overrides = append(config.Overrides, FleetOverride{
+				ImageId:          *comboAMI,
+				InstanceType:     pick.Instance.InstanceType,
+				SubnetId:         subnet,
+				WeightedCapacity: float64(pick.Instance.VCPUS),
+			})
+		}
+	}
+
+	request := CreateFleetRequest{
+		Type:        "maintain",
+		SpotOptions: map[string]string{"AllocationStrategy": "price-capacity-optimized"},
+		TargetCapacitySpecification: map[string]interface{}{
+			"TotalTargetCapacity":       combo.VCPUS,
+			"DefaultTargetCapacityType": "spot",
+		},
+		LaunchTemplateConfigs: []FleetLaunchTemplateConfig{config},
+	}
+	return marshalFleetRequest(request)
+}
+
+// marshalFleetRequest indents a request, keeping the <PLACEHOLDER> values readable
+func marshalFleetRequest(request interface{}) ([]byte, error) {
+	var b bytes.Buffer
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(request); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(b.Bytes(), "\n"), nil
+}
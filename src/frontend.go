@@ -0,0 +1,41 @@
+package main
+
+import (
+	"embed"
+	"errors"
+	"io/fs"
+	"net/http"
+)
+
+// The site is embedded so serve needs nothing but the binary. docs/ is the
+// source; the copies in src/frontend are generated from it with go generate,
+// and TestEmbeddedFrontend fails when they are out of date.
+//
+//go:generate sh -c "for f in index.html health.html; do { echo '<!-- Code generated by go generate from docs/'${DOLLAR}f'. DO NOT EDIT. -->'; cat ../docs/${DOLLAR}f; } > frontend/${DOLLAR}f; done"
+//go:generate sh -c "{ echo '/* Code generated by go generate from docs/styles.css. DO NOT EDIT. */'; cat ../docs/styles.css; } > frontend/styles.css"
+//go:embed frontend
+var embeddedFrontend embed.FS
+
+// frontendFS serves files from dir, falling back to the embedded site for
+// those it lacks, so a data-only directory still gets the full frontend
+type frontendFS struct {
+	dir      http.FileSystem
+	embedded http.FileSystem
+}
+
+// newFrontendFS overlays dir on the embedded site
+func newFrontendFS(dir string) frontendFS {
+	site, err := fs.Sub(embeddedFrontend, "frontend")
+	if err != nil {
+		panic(err) // the directory is embedded at build time
+	}
+	return frontendFS{http.Dir(dir), http.FS(site)}
+}
+
+func (f frontendFS) Open(name string) (http.File, error) {
+	file, err := f.dir.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return f.embedded.Open(name)
+	}
+	return file, err
+}
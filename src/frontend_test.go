@@ -0,0 +1,33 @@
+package main
+
+import (
+	"bytes"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestEmbeddedFrontend checks the embedded site was generated from the
+// current docs/, the only copy to edit
+func TestEmbeddedFrontend(t *testing.T) {
+	files, err := fs.ReadDir(embeddedFrontend, "frontend")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		embedded, err := fs.ReadFile(embeddedFrontend, "frontend/"+file.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		source, err := os.ReadFile(filepath.Join("..", "docs", file.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Drop the generated-file header
+		_, embedded, _ = bytes.Cut(embedded, []byte("\n"))
+		if !bytes.Equal(embedded, source) {
+			t.Errorf("src/frontend/%s is out of date with docs/%s; run go generate frontend.go in src", file.Name(), file.Name())
+		}
+	}
+}
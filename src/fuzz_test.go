@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"net/url"
+	"testing"
+)
+
+// The fuzz targets run their seeds with go test; search for new failing
+// inputs with e.g. go test -run '^$' -fuzz FuzzDecodePrices src/*.go
+
+func FuzzDecodePrices(f *testing.F) {
+	f.Add([]byte(`{"Prices":[{"InstanceType":"c6g.4xlarge","VCPUS":16,"Memory":"32 GiB","SpotSavingRate":"67%","SpotPrice":"0.2211","Cost":0.6700}]}`))
+	f.Add([]byte(`{"prices":[{"InstanceType":"m7g.large","VCPUS":2,"Memory":"8GiB","SpotSavingRate":"","SpotPrice":"NaN","Cost":"n/a"}],"other":[1,2]}`))
+	f.Add([]byte(`{"Prices":[`))
+	f.Add([]byte(`[]`))
+	f.Fuzz(func(t *testing.T, content []byte) {
+		instances, err := decodePrices(bytes.NewReader(content), func(Instance) bool { return true })
+		if err != nil {
+			return
+		}
+		if err := validateInstances(instances); err != nil {
+			t.Errorf("decoded instances fail validation: %v", err)
+		}
+	})
+}
+
+func FuzzParseRegions(f *testing.F) {
+	f.Add([]byte(`{"Europe (Ireland)":{"name":"Europe (Ireland)","code":"eu-west-1","type":"AWS Region","label":"Europe (Ireland)","continent":"Europe"}}`))
+	f.Add([]byte(`{"a":{"code":"x","type":"AWS Local Zone"}}`))
+	f.Add(bundledLocations)
+	f.Fuzz(func(t *testing.T, content []byte) {
+		regions, err := parseRegions(content)
+		if err == nil && len(regions) == 0 {
+			t.Error("parseRegions returned no region and no error")
+		}
+	})
+}
+
+func FuzzParsePrice(f *testing.F) {
+	for _, seed := range []string{"0.2211", "", "1e400", "-0.5", "NaN", " 0.1", "0x1p-2"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		parsePrice(s)
+
+		// Upstream prices are kept verbatim when numeric and dropped otherwise
+		var price upstreamPrice
+		quoted, _ := json.Marshal(s)
+		if err := price.UnmarshalJSON(quoted); err != nil {
+			t.Fatal(err)
+		}
+		if price != "" && parsePrice(string(price)) != parsePrice(s) {
+			t.Errorf("upstream price %q decoded as %q", s, price)
+		}
+	})
+}
+
+func FuzzParseLeadingNumber(f *testing.F) {
+	for _, seed := range []string{"32 GiB", "0.5GiB", "67%", "", "GiB", "1.2.3 GiB", "..."} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		if n := parseLeadingNumber(s); n < 0 || math.IsNaN(n) {
+			t.Errorf("parseLeadingNumber(%q) = %v", s, n)
+		}
+	})
+}
+
+func FuzzParseMatchQuery(f *testing.F) {
+	for _, seed := range []string{"vcpu=8&memory=32&arch=arm64&max_price=0.2", "arch=arm64,x86_64&region=eu-west-1&limit=5", "vcpu=-1", "max_price=0", "max_price=NaN", "memory=+Inf", "limit=100000", "%zz"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, raw string) {
+		params, err := url.ParseQuery(raw)
+		if err != nil {
+			return
+		}
+		query, err := parseMatchQuery(params)
+		if err != nil {
+			return
+		}
+		finite := !math.IsNaN(query.MinMemory) && !math.IsInf(query.MinMemory, 0) && !math.IsNaN(query.MaxPrice) && !math.IsInf(query.MaxPrice, 0)
+		if !finite || query.MinVCPUS < 0 || query.MinMemory < 0 || query.MaxPrice < 0 || query.Limit <= 0 || query.Limit > maxQueryLimit {
+			t.Errorf("parseMatchQuery(%q) accepted %+v", raw, query)
+		}
+	})
+}
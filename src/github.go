@@ -0,0 +1,333 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+)
+
+const (
+	githubAPI     = "https://api.github.com"
+	githubUploads = "https://uploads.github.com"
+)
+
+// errGitHubNotFound is returned when the GitHub API answers 404
+var errGitHubNotFound = errors.New("not found")
+
+// GitHubClient is a minimal client for the GitHub REST API
+type GitHubClient struct {
+	Token string
+	Repo  string // owner/name
+}
+
+// newGitHubClientFromEnv builds a client from GITHUB_TOKEN and GITHUB_REPOSITORY
+func newGitHubClientFromEnv() (*GitHubClient, error) {
+	token := os.Getenv("GITHUB_TOKEN")
+	repo := os.Getenv("GITHUB_REPOSITORY")
+	if token == "" || repo == "" {
+		return nil, errors.New("GITHUB_TOKEN and GITHUB_REPOSITORY must be set")
+	}
+	return &GitHubClient{Token: token, Repo: repo}, nil
+}
+
+// do sends a JSON request to the GitHub API and decodes the JSON response into out
+func (c *GitHubClient) do(method, path string, in, out interface{}) error {
+	var body io.Reader
+	contentType := ""
+	if in != nil {
+		payload, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(payload)
+		contentType = "application/json"
+	}
+	return c.send(method, githubAPI+path, contentType, body, out)
+}
+
+// send issues an authenticated request to rawURL and decodes the JSON response into out
+func (c *GitHubClient) send(method, rawURL, contentType string, body io.Reader, out interface{}) error {
+	req, err := http.NewRequest(method, rawURL, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%s %s: %w", method, rawURL, errGitHubNotFound)
+	}
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("%s %s: %s: %s", method, rawURL, resp.Status, msg)
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// defaultBranch returns the repository's default branch
+func (c *GitHubClient) defaultBranch() (string, error) {
+	var repo struct {
+		DefaultBranch string `json:"default_branch"`
+	}
+	err := c.do("GET", "/repos/"+c.Repo, nil, &repo)
+	return repo.DefaultBranch, err
+}
+
+// branchHead returns the SHA of the commit branch points at
+func (c *GitHubClient) branchHead(branch string) (string, error) {
+	var ref struct {
+		Object struct {
+			SHA string `json:"sha"`
+		} `json:"object"`
+	}
+	err := c.do("GET", "/repos/"+c.Repo+"/git/ref/heads/"+branch, nil, &ref)
+	return ref.Object.SHA, err
+}
+
+// createBranch creates branch pointing at the head of base
+func (c *GitHubClient) createBranch(branch, base string) error {
+	head, err := c.branchHead(base)
+	if err != nil {
+		return err
+	}
+	return c.do("POST", "/repos/"+c.Repo+"/git/refs", map[string]string{
+		"ref": "refs/heads/" + branch,
+		"sha": head,
+	}, nil)
+}
+
+// commitFiles creates or replaces files, keyed by path, on branch with a
+// single commit. The contents API commits one file at a time, so the commit
+// is built from a tree with the Git data API instead.
+func (c *GitHubClient) commitFiles(branch string, files map[string][]byte, message string) error {
+	head, err := c.branchHead(branch)
+	if err != nil {
+		return err
+	}
+	var parent struct {
+		Tree struct {
+			SHA string `json:"sha"`
+		} `json:"tree"`
+	}
+	if err := c.do("GET", "/repos/"+c.Repo+"/git/commits/"+head, nil, &parent); err != nil {
+		return err
+	}
+
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	entries := make([]map[string]string, 0, len(paths))
+	for _, path := range paths {
+		entries = append(entries, map[string]string{
+			"path":    path,
+			"mode":    "100644",
+			"type":    "blob",
+			"content": string(files[path]),
+		})
+	}
+	var tree, commit struct {
+		SHA string `json:"sha"`
+	}
+	err = c.do("POST", "/repos/"+c.Repo+"/git/trees", map[string]interface{}{
+		"base_tree": parent.Tree.SHA,
+		"tree":      entries,
+	}, &tree)
+	if err != nil {
+		return err
+	}
+	err = c.do("POST", "/repos/"+c.Repo+"/git/commits", map[string]interface{}{
+		"message": message,
+		"tree":    tree.SHA,
+		"parents": []string{head},
+	}, &commit)
+	if err != nil {
+		return err
+	}
+	return c.do("PATCH", "/repos/"+c.Repo+"/git/refs/heads/"+branch, map[string]string{"sha": commit.SHA}, nil)
+}
+
+// openPullRequest opens a pull request from head into base and returns its URL
+func (c *GitHubClient) openPullRequest(head, base, title, body string) (string, error) {
+	var pr struct {
+		HTMLURL string `json:"html_url"`
+	}
+	err := c.do("POST", "/repos/"+c.Repo+"/pulls", map[string]string{
+		"title": title,
+		"head":  head,
+		"base":  base,
+		"body":  body,
+	}, &pr)
+	return pr.HTMLURL, err
+}
+
+// openDataPullRequest commits files, keyed by path, to a new branch and
+// opens a pull request against base describing the changes
+func openDataPullRequest(files map[string][]byte, diff SpotDiff, base string) (string, error) {
+	client, err := newGitHubClientFromEnv()
+	if err != nil {
+		return "", err
+	}
+
+	if base == "" {
+		if base, err = client.defaultBranch(); err != nil {
+			return "", err
+		}
+	}
+
+	now := clock().UTC()
+	branch := "spot-data/" + now.Format("20060102-150405")
+	if err := client.createBranch(branch, base); err != nil {
+		return "", err
+	}
+
+	title := "Update spot data " + now.Format("2006-01-02 15:04 UTC")
+	if err := client.commitFiles(branch, files, title); err != nil {
+		return "", err
+	}
+
+	body := "Automated spot data refresh.\n\n" + diff.Markdown()
+	return client.openPullRequest(branch, base, title, body)
+}
+
+// pullRequestFiles lists the files a data pull request commits, keyed by
+// path: the dataset, which is not written in place, and the files the site
+// reads next to it as this run wrote them, each with its compact copy when
+// --minified is set, since the site prefers those
+func pullRequestFiles(data SpotData, content []byte) (map[string][]byte, error) {
+	files := map[string][]byte{dataFile: content}
+	if *minifiedJSON {
+		var minified bytes.Buffer
+		if err := writeMinifiedSpotData(&minified, data); err != nil {
+			return nil, err
+		}
+		files[minifiedName(dataFile)] = minified.Bytes()
+	}
+
+	for _, name := range []string{*statusFile, *diffFile, *leaderboardFile, *heatmapFile, *eventsFile} {
+		if name == "" {
+			continue
+		}
+		names := []string{name}
+		if *minifiedJSON {
+			names = append(names, minifiedName(name))
+		}
+		for _, name := range names {
+			content, err := os.ReadFile(name)
+			if os.IsNotExist(err) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			files[name] = content
+		}
+	}
+	return files, nil
+}
+
+// githubRelease is the subset of a GitHub release used for publishing assets
+type githubRelease struct {
+	ID      int64  `json:"id"`
+	HTMLURL string `json:"html_url"`
+	Assets  []struct {
+		ID   int64  `json:"id"`
+		Name string `json:"name"`
+	} `json:"assets"`
+}
+
+// releaseForTag returns the release for tag, creating it when missing
+func (c *GitHubClient) releaseForTag(tag, name, body string) (githubRelease, error) {
+	var release githubRelease
+	err := c.do("GET", "/repos/"+c.Repo+"/releases/tags/"+tag, nil, &release)
+	if !errors.Is(err, errGitHubNotFound) {
+		return release, err
+	}
+	err = c.do("POST", "/repos/"+c.Repo+"/releases", map[string]interface{}{
+		"tag_name": tag,
+		"name":     name,
+		"body":     body,
+	}, &release)
+	return release, err
+}
+
+// uploadAsset attaches content to the release, replacing an asset of the same name
+func (c *GitHubClient) uploadAsset(release githubRelease, name, contentType string, content []byte) error {
+	for _, asset := range release.Assets {
+		if asset.Name == name {
+			if err := c.do("DELETE", fmt.Sprintf("/repos/%s/releases/assets/%d", c.Repo, asset.ID), nil, nil); err != nil {
+				return err
+			}
+		}
+	}
+	uploadURL := fmt.Sprintf("%s/repos/%s/releases/%d/assets?name=%s", githubUploads, c.Repo, release.ID, url.QueryEscape(name))
+	return c.send("POST", uploadURL, contentType, bytes.NewReader(content), nil)
+}
+
+// publishRelease attaches the day's snapshot as JSON, CSV and SHA-256
+// checksums to a release tagged spot-data-YYYY-MM-DD
+func publishRelease(data SpotData, content []byte) (string, error) {
+	client, err := newGitHubClientFromEnv()
+	if err != nil {
+		return "", err
+	}
+
+	csvContent, err := encodeSpotDataCSV(data)
+	if err != nil {
+		return "", err
+	}
+
+	assets := []struct {
+		name, contentType string
+		content           []byte
+	}{
+		{"spot_data.json", "application/json", content},
+		{"spot_data.csv", "text/csv", csvContent},
+	}
+
+	var checksums bytes.Buffer
+	for _, asset := range assets {
+		fmt.Fprintf(&checksums, "%x  %s\n", sha256.Sum256(asset.content), asset.name)
+	}
+
+	day := clock().UTC().Format("2006-01-02")
+	body := "Daily EC2 spot price snapshot.\n\n" + attributionNotice(data.License, data.Attribution)
+	release, err := client.releaseForTag("spot-data-"+day, "Spot data "+day, body)
+	if err != nil {
+		return "", err
+	}
+
+	for _, asset := range assets {
+		if err := client.uploadAsset(release, asset.name, asset.contentType, asset.content); err != nil {
+			return "", err
+		}
+	}
+	if err := client.uploadAsset(release, "SHA256SUMS", "text/plain", checksums.Bytes()); err != nil {
+		return "", err
+	}
+
+	return release.HTMLURL, nil
+}
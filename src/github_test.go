@@ -0,0 +1,46 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestPullRequestFilesIncludeSiteFiles(t *testing.T) {
+	dir := t.TempDir()
+	setVar(t, &dataFile, filepath.Join(dir, "spot_data.json"))
+	for name, file := range map[string]string{
+		"status-file": "status.json",
+		"diff-file":   "diff_latest.json",
+		"leaderboard": "leaderboard.json",
+		"heatmap":     "",
+		"events":      "events.json",
+	} {
+		if file != "" {
+			file = filepath.Join(dir, file)
+		}
+		setFlag(t, name, file)
+	}
+	// The run wrote these; the events file was not written
+	for _, name := range []string{"status.json", "status.min.json", "diff_latest.json", "diff_latest.min.json", "leaderboard.json", "leaderboard.min.json"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("{}\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := pullRequestFiles(readFixture(t), []byte("{}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for path := range files {
+		got = append(got, filepath.Base(path))
+	}
+	sort.Strings(got)
+	want := []string{"diff_latest.json", "diff_latest.min.json", "leaderboard.json", "leaderboard.min.json", "spot_data.json", "spot_data.min.json", "status.json", "status.min.json"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("pull request files = %v, want %v", got, want)
+	}
+}
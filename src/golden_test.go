@@ -0,0 +1,71 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestOutputFormats renders every published format of the fixture dataset
+// and compares it with its golden file, so changing a format is a
+// deliberate edit of testdata/golden rather than a silent change
+func TestOutputFormats(t *testing.T) {
+	data := readFixture(t)
+	deriveSections(&data)
+	dir := t.TempDir()
+
+	// Formats written to a file, by golden file name
+	writers := map[string]func(filename string) error{
+		"spot_data.json":      func(filename string) error { return writeDataFile(filename, data) },
+		"spot_data.libsonnet": func(filename string) error { return writeJsonnetLibrary(filename, data) },
+		"spot_data.cue":       func(filename string) error { return writeCUEPackage(filename, data) },
+		"spot_vars.yml":       func(filename string) error { return writeAnsibleVars(filename, "spot_", data) },
+		"spot.pkrvars.hcl":    func(filename string) error { return writePackerVars(filename, data) },
+		"node_labels.json":    func(filename string) error { return writeNodeLabels(filename, data) },
+		"opencost.csv":        func(filename string) error { return writeOpenCostCSV(filename, data) },
+		"heatmap.json":        func(filename string) error { return writeHeatmap(filename, data) },
+		"spot_data.min.json": func(filename string) error {
+			return writeMinifiedDataFile(filepath.Join(dir, "spot_data.json"), data)
+		},
+	}
+	for name, write := range writers {
+		t.Run(name, func(t *testing.T) {
+			filename := filepath.Join(dir, name)
+			if err := write(filename); err != nil {
+				t.Fatal(err)
+			}
+			got, err := os.ReadFile(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkGolden(t, name, got)
+		})
+	}
+
+	t.Run("spot_data.csv", func(t *testing.T) {
+		got, err := encodeSpotDataCSV(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkGolden(t, "spot_data.csv", got)
+	})
+
+	t.Run("deals.html", func(t *testing.T) {
+		template, err := os.ReadFile(filepath.Join("testdata", "deals.html.tmpl"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		name := filepath.Join(dir, "deals.html.tmpl")
+		if err := os.WriteFile(name, template, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := renderTemplates([]string{name}, data); err != nil {
+			t.Fatal(err)
+		}
+		got, err := os.ReadFile(filepath.Join(dir, "deals.html"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkGolden(t, "deals.html", got)
+	})
+}
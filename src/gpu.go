@@ -0,0 +1,174 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+)
+
+var (
+	gpuFlags   = flag.NewFlagSet("gpu", flag.ExitOnError)
+	gpuRegions = gpuFlags.String("regions", "", "comma-separated regions to compare (defaults to every region)")
+	gpuModel   = gpuFlags.String("model", "", "only list instances with this accelerator, e.g. A10G or H100")
+	gpuSort    = gpuFlags.String("sort", "gpu-hour", "rank by price per gpu-hour or per tflop-hour")
+	gpuLimit   = gpuFlags.Int("limit", 20, "number of offers to list")
+)
+
+// gpuModelTFLOPS is the dense FP16/BF16 tensor throughput of each
+// accelerator in TFLOPS, per the vendors' datasheets
+var gpuModelTFLOPS = map[string]float64{
+	"V100":      125,
+	"T4":        65,
+	"T4G":       65,
+	"A10G":      125,
+	"A100":      312,
+	"L4":        121,
+	"L40S":      362,
+	"H100":      989,
+	"H200":      989,
+	"Trainium":  190,
+	"Trainium2": 667,
+}
+
+// GPUSpec is the accelerator fit of an instance type
+type GPUSpec struct {
+	Model string
+	GPUs  int
+}
+
+// gpuSpecs lists the accelerators of the EC2 instance types suited to ML training
+var gpuSpecs = map[string]GPUSpec{
+	"p3.2xlarge": {"V100", 1}, "p3.8xlarge": {"V100", 4}, "p3.16xlarge": {"V100", 8}, "p3dn.24xlarge": {"V100", 8},
+	"p4d.24xlarge": {"A100", 8}, "p4de.24xlarge": {"A100", 8},
+	"p5.48xlarge": {"H100", 8}, "p5e.48xlarge": {"H200", 8}, "p5en.48xlarge": {"H200", 8},
+	"g4dn.xlarge": {"T4", 1}, "g4dn.2xlarge": {"T4", 1}, "g4dn.4xlarge": {"T4", 1}, "g4dn.8xlarge": {"T4", 1},
+	"g4dn.16xlarge": {"T4", 1}, "g4dn.12xlarge": {"T4", 4}, "g4dn.metal": {"T4", 8},
+	"g5g.xlarge": {"T4G", 1}, "g5g.2xlarge": {"T4G", 1}, "g5g.4xlarge": {"T4G", 1}, "g5g.8xlarge": {"T4G", 1},
+	"g5g.16xlarge": {"T4G", 2}, "g5g.metal": {"T4G", 2},
+	"g5.xlarge": {"A10G", 1}, "g5.2xlarge": {"A10G", 1}, "g5.4xlarge": {"A10G", 1}, "g5.8xlarge": {"A10G", 1},
+	"g5.16xlarge": {"A10G", 1}, "g5.12xlarge": {"A10G", 4}, "g5.24xlarge": {"A10G", 4}, "g5.48xlarge": {"A10G", 8},
+	"g6.xlarge": {"L4", 1}, "g6.2xlarge": {"L4", 1}, "g6.4xlarge": {"L4", 1}, "g6.8xlarge": {"L4", 1},
+	"g6.16xlarge": {"L4", 1}, "g6.12xlarge": {"L4", 4}, "g6.24xlarge": {"L4", 4}, "g6.48xlarge": {"L4", 8},
+	"g6e.xlarge": {"L40S", 1}, "g6e.2xlarge": {"L40S", 1}, "g6e.4xlarge": {"L40S", 1}, "g6e.8xlarge": {"L40S", 1},
+	"g6e.16xlarge": {"L40S", 1}, "g6e.12xlarge": {"L40S", 4}, "g6e.24xlarge": {"L40S", 4}, "g6e.48xlarge": {"L40S", 8},
+	"trn1.2xlarge": {"Trainium", 1}, "trn1.32xlarge": {"Trainium", 16}, "trn1n.32xlarge": {"Trainium", 16},
+	"trn2.48xlarge": {"Trainium2", 16},
+}
+
+// GPUOffer is the spot price of an accelerated instance type in a region
+type GPUOffer struct {
+	Instance Instance
+	Region   string
+	Spec     GPUSpec
+	// PerGPUHour and PerTFLOPHour divide the hourly spot price by the
+	// accelerator count and their combined throughput
+	PerGPUHour   float64
+	PerTFLOPHour float64
+}
+
+// gpuOffers prices the accelerated instances of each region. Unlike the
+// regional deals they are not limited by vCPU count or savings rate.
+func gpuOffers(regions []string) []GPUOffer {
+	var offers []GPUOffer
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, region := range regions {
+		if policy.ForbidsRegion(region) {
+			continue
+		}
+		wg.Add(1)
+		go func(r string) {
+			defer wg.Done()
+			instances, err := fetchRegionPrices(r, "", func(instance Instance) bool {
+				_, ok := gpuSpecs[instance.InstanceType]
+				return ok && instanceFilter.Permits(instance.InstanceType)
+			})
+			if err != nil {
+				log.Printf("Error getting GPU prices for region %s: %v", r, err)
+				return
+			}
+			applyInterruptionRates(r, instances)
+			instances = policy.Filter(r, instances)
+
+			mu.Lock()
+			defer mu.Unlock()
+			for _, instance := range instances {
+				price := parsePrice(instance.SpotPrice)
+				spec := gpuSpecs[instance.InstanceType]
+				if price <= 0 {
+					continue
+				}
+				offers = append(offers, GPUOffer{
+					Instance:     instance,
+					Region:       r,
+					Spec:         spec,
+					PerGPUHour:   price / float64(spec.GPUs),
+					PerTFLOPHour: price / (float64(spec.GPUs) * gpuModelTFLOPS[spec.Model]),
+				})
+			}
+		}(region)
+	}
+	wg.Wait()
+	return offers
+}
+
+// runGPU implements the gpu command: it compares the spot price of
+// accelerated instances per GPU-hour and per TFLOP-hour across regions
+func runGPU(args []string) {
+	gpuFlags.Parse(args)
+
+	if (*gpuSort != "gpu-hour" && *gpuSort != "tflop-hour") || *gpuLimit <= 0 {
+		fmt.Fprintln(os.Stderr, "usage: gpu [--regions <region,...>] [--model <name>] [--sort gpu-hour|tflop-hour] [--limit 20]")
+		gpuFlags.PrintDefaults()
+		os.Exit(2)
+	}
+
+	regions := splitParam([]string{*gpuRegions})
+	if len(regions) == 0 {
+		var err error
+		if regions, err = fetchRegions(); err != nil {
+			log.Fatalf("Error fetching regions: %v", err)
+		}
+	}
+
+	var offers []GPUOffer
+	for _, offer := range gpuOffers(regions) {
+		if *gpuModel == "" || strings.EqualFold(offer.Spec.Model, *gpuModel) {
+			offers = append(offers, offer)
+		}
+	}
+	if len(offers) == 0 {
+		log.Fatalf("No spot price found for a GPU instance")
+	}
+
+	sort.Slice(offers, func(i, j int) bool {
+		a, b := offers[i].PerGPUHour, offers[j].PerGPUHour
+		if *gpuSort == "tflop-hour" {
+			a, b = offers[i].PerTFLOPHour, offers[j].PerTFLOPHour
+		}
+		if a != b {
+			return a < b
+		}
+		return offers[i].Region+offers[i].Instance.InstanceType < offers[j].Region+offers[j].Instance.InstanceType
+	})
+	if len(offers) > *gpuLimit {
+		offers = offers[:*gpuLimit]
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "INSTANCE TYPE\tREGION\tGPUS\tMODEL\tSPOT PRICE\tPER GPU-HOUR\tPER TFLOP-HOUR\tINTERRUPTION")
+	for _, offer := range offers {
+		rate := offer.Instance.InterruptionRate
+		if rate == "" {
+			rate = "unknown"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t$%s\t$%.4f\t$%.6f\t%s\n", offer.Instance.InstanceType, offer.Region, offer.Spec.GPUs, offer.Spec.Model,
+			offer.Instance.SpotPrice, offer.PerGPUHour, offer.PerTFLOPHour, rate)
+	}
+	w.Flush()
+}
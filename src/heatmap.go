@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"math"
+	"sort"
+	"strings"
+)
+
+var heatmapFile = flag.String("heatmap", "", "also write a region by instance family matrix of prices per vCPU, for the site's heat map, to this file")
+
+// Heatmap is the cheapest price per vCPU of every instance family in every
+// region, laid out for rendering without further computation on the site
+type Heatmap struct {
+	LastUpdated string   `json:"last_updated"`
+	Regions     []string `json:"regions"`  // rows
+	Families    []string `json:"families"` // columns
+	// PricePerVCPU holds one row per region and one column per family,
+	// null where a region publishes no deal of the family
+	PricePerVCPU [][]*float64 `json:"price_per_vcpu"`
+	// Normalized places each price between the cheapest (0) and the most
+	// expensive (1) cell of the matrix, ready to map onto a color scale. The
+	// scale is logarithmic, since prices per vCPU span orders of magnitude.
+	Normalized [][]*float64 `json:"normalized"`
+	Min        float64      `json:"min"`
+	Max        float64      `json:"max"`
+}
+
+// heatmap builds the matrix from the regional deals
+func heatmap(data SpotData) Heatmap {
+	cheapest := map[string]map[string]float64{}
+	families := map[string]bool{}
+	for region, instances := range data.Regions {
+		for _, instance := range instances {
+			price := parsePrice(instance.SpotPrice)
+			if price <= 0 || instance.VCPUS <= 0 {
+				continue
+			}
+			family, _, _ := strings.Cut(instance.InstanceType, ".")
+			perVCPU := price / float64(instance.VCPUS)
+			if cheapest[region] == nil {
+				cheapest[region] = map[string]float64{}
+			}
+			if current, ok := cheapest[region][family]; !ok || perVCPU < current {
+				cheapest[region][family] = perVCPU
+			}
+			families[family] = true
+		}
+	}
+
+	h := Heatmap{LastUpdated: data.LastUpdated, Min: math.Inf(1), Max: math.Inf(-1)}
+	for region := range cheapest {
+		h.Regions = append(h.Regions, region)
+	}
+	for family := range families {
+		h.Families = append(h.Families, family)
+	}
+	sort.Strings(h.Regions)
+	sort.Strings(h.Families)
+
+	for _, region := range h.Regions {
+		for _, perVCPU := range cheapest[region] {
+			h.Min = math.Min(h.Min, perVCPU)
+			h.Max = math.Max(h.Max, perVCPU)
+		}
+	}
+	if len(h.Regions) == 0 {
+		h.Min, h.Max = 0, 0
+	}
+
+	for _, region := range h.Regions {
+		prices := make([]*float64, len(h.Families))
+		normalized := make([]*float64, len(h.Families))
+		for i, family := range h.Families {
+			perVCPU, ok := cheapest[region][family]
+			if !ok {
+				continue
+			}
+			price := math.Round(perVCPU*1e6) / 1e6
+			scaled := 0.0
+			if h.Max > h.Min {
+				scaled = math.Round(math.Log(perVCPU/h.Min)/math.Log(h.Max/h.Min)*1000) / 1000
+			}
+			prices[i], normalized[i] = &price, &scaled
+		}
+		h.PricePerVCPU = append(h.PricePerVCPU, prices)
+		h.Normalized = append(h.Normalized, normalized)
+	}
+	h.Min = math.Round(h.Min*1e6) / 1e6
+	h.Max = math.Round(h.Max*1e6) / 1e6
+	return h
+}
+
+// writeHeatmap writes the heat map matrix of the dataset
+func writeHeatmap(filename string, data SpotData) error {
+	content, err := json.MarshalIndent(heatmap(data), "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeJSONFile(filename, content)
+}
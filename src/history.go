@@ -0,0 +1,358 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Observation is a spot price seen for an instance type in a region at a point in time
+type Observation struct {
+	Time         string `json:"time"`
+	Region       string `json:"region"`
+	InstanceType string `json:"instanceType"`
+	Price        string `json:"price"`
+}
+
+// recordHistory appends the fresh prices of data to the price history in
+// filename, compacting it when it grew too large, and derives the
+// recommended max prices, percentiles and floors of data from its trailing
+// window. It returns the price events the window shows.
+func recordHistory(filename string, data *SpotData) ([]PriceEvent, error) {
+	if err := appendHistory(filename, *data); err != nil {
+		return nil, fmt.Errorf("appending price history: %w", err)
+	}
+	cutoff := clock().Add(-*historyWindow)
+	if info, err := os.Stat(filename); err == nil && info.Size() > *historyCompact {
+		archived, err := compactHistory(filename, cutoff)
+		if err != nil {
+			return nil, fmt.Errorf("compacting price history: %w", err)
+		}
+		log.Printf("Archived %d price observations older than %s", archived, *historyWindow)
+	}
+	observations, err := readHistory(filename, cutoff)
+	if err != nil {
+		return nil, fmt.Errorf("reading price history: %w", err)
+	}
+	applyRecommendedMaxPrices(data, observations)
+	applyPricePercentiles(data, observations)
+	applyPriceFloors(data, observations)
+	return detectPriceEvents(*data, observations, *spikeWindow, *spikePercent), nil
+}
+
+// appendHistory records one observation per fetched instance as JSON lines
+func appendHistory(filename string, data SpotData) error {
+	file, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+	encoder := json.NewEncoder(w)
+	for region, instances := range data.Regions {
+		for _, instance := range instances {
+			observation := Observation{data.LastUpdated, region, instance.InstanceType, instance.SpotPrice}
+			if err := encoder.Encode(observation); err != nil {
+				return err
+			}
+		}
+	}
+	return w.Flush()
+}
+
+// readHistory streams the archived and active history files and returns
+// observations made at or after since
+func readHistory(filename string, since time.Time) ([]Observation, error) {
+	files, err := historyArchives(filename, since)
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, filename)
+
+	var observations []Observation
+	for _, name := range files {
+		err := scanHistoryFile(name, func(observation Observation, t time.Time) {
+			if !t.Before(since) {
+				observations = append(observations, observation)
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return observations, nil
+}
+
+// scanHistoryFile calls fn for every observation with a valid timestamp in a
+// plain or gzipped JSON lines file, treating a missing file as empty.
+// Undecodable lines, such as one truncated by an interrupted append, are
+// logged and skipped.
+func scanHistoryFile(filename string, fn func(Observation, time.Time)) error {
+	file, err := os.Open(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	if strings.HasSuffix(filename, ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return fmt.Errorf("%s: %w", filename, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		var observation Observation
+		if err := json.Unmarshal(scanner.Bytes(), &observation); err != nil {
+			log.Printf("Skipping %s:%d: %v", filename, line, err)
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, observation.Time)
+		if err != nil {
+			continue
+		}
+		fn(observation, t)
+	}
+	return scanner.Err()
+}
+
+// historyArchivePath names the gzipped monthly archive of the history file
+func historyArchivePath(filename string, month time.Time) string {
+	return strings.TrimSuffix(filename, ".jsonl") + "-" + month.Format("2006-01") + ".jsonl.gz"
+}
+
+// historyArchives lists, oldest first, the monthly archives that may hold
+// observations made at or after since
+func historyArchives(filename string, since time.Time) ([]string, error) {
+	matches, err := filepath.Glob(strings.TrimSuffix(filename, ".jsonl") + "-*.jsonl.gz")
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(matches)
+
+	first := historyArchivePath(filename, since)
+	var archives []string
+	for _, match := range matches {
+		if match >= first {
+			archives = append(archives, match)
+		}
+	}
+	return archives, nil
+}
+
+// compactHistory moves observations older than cutoff out of the active
+// history file into gzipped monthly archives. Both files are streamed, so
+// compaction never holds the history in memory; archives grow by appending
+// gzip members, which readers decode as one stream.
+func compactHistory(filename string, cutoff time.Time) (int, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	type archive struct {
+		file *os.File
+		gz   *gzip.Writer
+	}
+	archives := make(map[string]*archive)
+	closeArchives := func() error {
+		var firstErr error
+		for _, a := range archives {
+			if err := a.gz.Close(); err != nil && firstErr == nil {
+				firstErr = err
+			}
+			if err := a.file.Close(); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+		return firstErr
+	}
+
+	kept := bufio.NewWriter(tmp)
+	archived := 0
+	var writeErr error
+	err = scanHistoryFile(filename, func(observation Observation, t time.Time) {
+		if writeErr != nil {
+			return
+		}
+		line, err := json.Marshal(observation)
+		if err != nil {
+			writeErr = err
+			return
+		}
+		line = append(line, '\n')
+
+		if !t.Before(cutoff) {
+			_, writeErr = kept.Write(line)
+			return
+		}
+
+		path := historyArchivePath(filename, t.UTC())
+		a, ok := archives[path]
+		if !ok {
+			file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+			if err != nil {
+				writeErr = err
+				return
+			}
+			a = &archive{file, gzip.NewWriter(file)}
+			archives[path] = a
+		}
+		_, writeErr = a.gz.Write(line)
+		archived++
+	})
+	if err == nil {
+		err = writeErr
+	}
+	if closeErr := closeArchives(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = kept.Flush()
+	}
+	if err == nil {
+		err = tmp.Close()
+	}
+	if err != nil {
+		return 0, err
+	}
+	return archived, os.Rename(tmp.Name(), filename)
+}
+
+// minPercentileSamples is the number of observations below which a price's
+// position in its history is too noisy to publish
+const minPercentileSamples = 10
+
+// applyPricePercentiles sets each instance's CheaperThan to the share of its
+// observed prices above the current one, e.g. "85%" when it is cheaper than
+// 85% of recent observations, so users can tell whether to act now or wait
+func applyPricePercentiles(data *SpotData, observations []Observation) {
+	series := priceSeries(observations)
+	for region, instances := range data.Regions {
+		for i, instance := range instances {
+			prices := series[region][instance.InstanceType]
+			current, err := strconv.ParseFloat(instance.SpotPrice, 64)
+			if len(prices) < minPercentileSamples || err != nil {
+				continue
+			}
+			above := 0
+			for _, price := range prices {
+				if price > current {
+					above++
+				}
+			}
+			instances[i].CheaperThan = fmt.Sprintf("%d%%", above*100/len(prices))
+		}
+	}
+}
+
+// priceSeries groups observed prices by region and instance type, in file order
+func priceSeries(observations []Observation) map[string]map[string][]float64 {
+	series := make(map[string]map[string][]float64)
+	for _, observation := range observations {
+		price, err := strconv.ParseFloat(observation.Price, 64)
+		if err != nil {
+			continue
+		}
+		if series[observation.Region] == nil {
+			series[observation.Region] = make(map[string][]float64)
+		}
+		series[observation.Region][observation.InstanceType] = append(series[observation.Region][observation.InstanceType], price)
+	}
+	return series
+}
+
+// percentile returns the nearest-rank percentile p (0-100) of values
+func percentile(values []float64, p float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// floorTolerance is how far above its family's floor, relative to it, a
+// price per vCPU still counts as at the floor
+const floorTolerance = 0.01
+
+// applyPriceFloors flags instances whose price per vCPU is at the floor of
+// their family in the region: the lowest price per vCPU any size of the
+// family was observed at in the history window. Such prices cannot be
+// expected to drop further, so there is no point waiting.
+func applyPriceFloors(data *SpotData, observations []Observation) {
+	vcpus := make(map[string]int)
+	for _, instances := range data.Regions {
+		for _, instance := range instances {
+			vcpus[instance.InstanceType] = instance.VCPUS
+		}
+	}
+
+	type familyFloor struct {
+		min     float64
+		samples int
+	}
+	floors := make(map[string]*familyFloor)
+	for _, observation := range observations {
+		price, err := strconv.ParseFloat(observation.Price, 64)
+		if err != nil || price <= 0 || vcpus[observation.InstanceType] == 0 {
+			continue
+		}
+		family, _, _ := strings.Cut(observation.InstanceType, ".")
+		perVCPU := price / float64(vcpus[observation.InstanceType])
+		floor := floors[observation.Region+"/"+family]
+		if floor == nil {
+			floor = &familyFloor{min: perVCPU}
+			floors[observation.Region+"/"+family] = floor
+		}
+		floor.min = math.Min(floor.min, perVCPU)
+		floor.samples++
+	}
+
+	for region, instances := range data.Regions {
+		for i, instance := range instances {
+			family, _, _ := strings.Cut(instance.InstanceType, ".")
+			floor := floors[region+"/"+family]
+			perVCPU := pricePerVCPU(instance)
+			instances[i].AtFloor = floor != nil && floor.samples >= minPercentileSamples && perVCPU <= floor.min*(1+floorTolerance)
+		}
+	}
+}
+
+// applyRecommendedMaxPrices sets each instance's RecommendedMaxPrice to the
+// 95th percentile of its observed prices
+func applyRecommendedMaxPrices(data *SpotData, observations []Observation) {
+	series := priceSeries(observations)
+	for region, instances := range data.Regions {
+		for i, instance := range instances {
+			if prices := series[region][instance.InstanceType]; len(prices) > 0 {
+				instances[i].RecommendedMaxPrice = strconv.FormatFloat(percentile(prices, 95), 'f', 4, 64)
+			}
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReadHistorySkipsTruncatedLine(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "price_history.jsonl")
+	content := `{"time":"2023-12-31T00:00:00Z","region":"eu-west-1","instanceType":"c6g.4xlarge","price":"0.1965"}
+{"time":"2023-12-31T12:00:00Z","region":"eu-west-1","instanceType":"c6g.4xlarge","price":"0.2010"}
+{"time":"2024-01-01T00:00:00Z","region":"eu-west-1","inst`
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	observations, err := readHistory(filename, testNow.Add(-7*24*time.Hour))
+	if err != nil {
+		t.Fatalf("readHistory: %v", err)
+	}
+	if len(observations) != 2 {
+		t.Errorf("read %d observations, want the 2 complete ones", len(observations))
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+var (
+	cacheDir = flag.String("cache-dir", "", "cache upstream GET responses in this directory, e.g. for repeated local runs; empty disables it")
+	cacheTTL = flag.Duration("cache-ttl", time.Hour, "how long cached responses are reused, unless cache_ttls in the config sets one for the host")
+)
+
+// cacheTransport answers upstream GET requests from gzipped bodies on disk,
+// keyed by URL. Fresh entries are reused without a request; stale ones are
+// still served when the upstream fails, so runs keep working offline.
+type cacheTransport struct {
+	next http.RoundTripper
+	dir  string
+	ttls map[string]time.Duration // per host, defaulting to ttl
+	ttl  time.Duration
+}
+
+// enableCache routes requests through the cache directory dir
+func enableCache(dir string, ttl time.Duration, ttls map[string]duration) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	t := &cacheTransport{next: http.DefaultTransport, dir: dir, ttl: ttl, ttls: make(map[string]time.Duration, len(ttls))}
+	for host, ttl := range ttls {
+		t.ttls[host] = time.Duration(ttl)
+	}
+	http.DefaultTransport = t
+	return nil
+}
+
+// path returns the cache file of a URL
+func (t *cacheTransport) path(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(t.dir, hex.EncodeToString(sum[:])+".gz")
+}
+
+// cached returns the body stored for path and whether it is still fresh
+func (t *cacheTransport) cached(path, host string) ([]byte, bool, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, false, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, false, err
+	}
+	defer file.Close()
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, false, err
+	}
+	body, err := io.ReadAll(io.LimitReader(reader, maxResponseBytes))
+	if err != nil {
+		return nil, false, err
+	}
+
+	ttl, ok := t.ttls[host]
+	if !ok {
+		ttl = t.ttl
+	}
+	return body, time.Since(info.ModTime()) < ttl, nil
+}
+
+func (t *cacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Authenticated requests are specific to their caller and never cached
+	if req.Method != http.MethodGet || req.Header.Get("Authorization") != "" {
+		return t.next.RoundTrip(req)
+	}
+
+	path := t.path(req.URL.String())
+	body, fresh, cacheErr := t.cached(path, req.URL.Host)
+	if cacheErr == nil && fresh {
+		return cachedResponse(req, body), nil
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || resp.StatusCode >= 500 {
+		if cacheErr == nil {
+			log.Printf("Upstream request for %s failed, using the stale cached response", req.URL)
+			if err == nil {
+				resp.Body.Close()
+			}
+			return cachedResponse(req, body), nil
+		}
+		return resp, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+
+	temp, err := os.CreateTemp(t.dir, ".response-*")
+	if err != nil {
+		log.Printf("Error caching %s: %v", req.URL, err)
+		return resp, nil
+	}
+	resp.Body = &cachingBody{ReadCloser: resp.Body, temp: temp, gzip: gzip.NewWriter(temp), path: path}
+	return resp, nil
+}
+
+// cachedResponse answers req with a cached body
+func cachedResponse(req *http.Request, body []byte) *http.Response {
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"X-Cache": []string{"HIT"}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// cachingBody writes a response body to the cache as it is read. Decoders
+// may stop before the end, so the rest is drained on Close; the entry only
+// replaces the previous one once the whole body arrived.
+type cachingBody struct {
+	io.ReadCloser
+	temp *os.File
+	gzip *gzip.Writer
+	path string
+	err  error
+}
+
+func (b *cachingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if b.err == nil {
+		_, b.err = b.gzip.Write(p[:n])
+	}
+	if err != nil && err != io.EOF && b.err == nil {
+		b.err = err
+	}
+	return n, err
+}
+
+func (b *cachingBody) Close() error {
+	if b.err == nil {
+		_, b.err = io.Copy(b.gzip, io.LimitReader(b.ReadCloser, maxResponseBytes))
+	}
+	if err := b.gzip.Close(); b.err == nil {
+		b.err = err
+	}
+	if err := b.temp.Close(); b.err == nil {
+		b.err = err
+	}
+	if b.err == nil {
+		b.err = os.Rename(b.temp.Name(), b.path)
+	}
+	if b.err != nil {
+		os.Remove(b.temp.Name())
+	}
+	return b.ReadCloser.Close()
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+	"unicode/utf8"
+)
+
+// Incident tool APIs; variables so tests can point them at local mock servers
+var (
+	pagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+	opsgenieAlertsURL  = "https://api.opsgenie.com/v2/alerts"
+)
+
+// incidentKey deduplicates pipeline failures into one open incident, which
+// the next successful run resolves
+const incidentKey = "ec2-spot-finder-refresh"
+
+var incidentFile = flag.String("incident-file", "docs/incident.json", "record the incident opened by a failed run here, so only the next successful run resolves it; empty disables resolving")
+
+// Incident is the record of an open pipeline incident
+type Incident struct {
+	OpenedAt string `json:"opened_at"`
+	Message  string `json:"message"` // of the run that opened it
+}
+
+// failRun reports a failure of the refresh pipeline to the incident tools
+// configured through PAGERDUTY_ROUTING_KEY and OPSGENIE_API_KEY, then exits
+// with code. Deal alerts are separate; this is about the dataset going stale.
+func failRun(code int, format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	opened := false
+	if key := os.Getenv("PAGERDUTY_ROUTING_KEY"); key != "" {
+		opened = true
+		if err := sendPagerDutyEvent(key, "trigger", message); err != nil {
+			log.Printf("Error triggering PagerDuty incident: %v", err)
+		}
+	}
+	if key := os.Getenv("OPSGENIE_API_KEY"); key != "" {
+		opened = true
+		alert := map[string]interface{}{
+			"message":     truncate("Spot data refresh failed: "+message, 130),
+			"alias":       incidentKey,
+			"description": message,
+			"priority":    "P2",
+			"source":      programName,
+		}
+		if err := postIncident(opsgenieAlertsURL, key, alert); err != nil {
+			log.Printf("Error creating Opsgenie alert: %v", err)
+		}
+	}
+	if opened {
+		if err := recordIncident(message); err != nil {
+			log.Printf("Error recording the open incident: %v", err)
+		}
+	}
+	log.Print(message)
+	stopProfiling()
+	os.Exit(code)
+}
+
+// recordIncident writes the incident file, keeping the opening time of an
+// incident already open
+func recordIncident(message string) error {
+	if *incidentFile == "" {
+		return nil
+	}
+	if _, err := os.Stat(*incidentFile); err == nil {
+		return nil
+	}
+	content, err := json.MarshalIndent(Incident{clock().UTC().Format(time.RFC3339), message}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(*incidentFile, append(content, '\n'), 0644)
+}
+
+// resolveIncident closes the pipeline incident recorded by an earlier failed
+// run, if any. The record is kept when closing fails, so the next run retries.
+func resolveIncident() {
+	if *incidentFile == "" {
+		return
+	}
+	if _, err := os.Stat(*incidentFile); err != nil {
+		return
+	}
+
+	resolved := true
+	if key := os.Getenv("PAGERDUTY_ROUTING_KEY"); key != "" {
+		if err := sendPagerDutyEvent(key, "resolve", ""); err != nil {
+			log.Printf("Error resolving PagerDuty incident: %v", err)
+			resolved = false
+		}
+	}
+	if key := os.Getenv("OPSGENIE_API_KEY"); key != "" {
+		closeURL := opsgenieAlertsURL + "/" + url.PathEscape(incidentKey) + "/close?identifierType=alias"
+		if err := postIncident(closeURL, key, map[string]string{"source": programName}); err != nil {
+			log.Printf("Error closing Opsgenie alert: %v", err)
+			resolved = false
+		}
+	}
+	if resolved {
+		if err := os.Remove(*incidentFile); err != nil {
+			log.Printf("Error removing the incident record: %v", err)
+		}
+	}
+}
+
+// sendPagerDutyEvent triggers or resolves the pipeline incident through the Events API v2
+func sendPagerDutyEvent(routingKey, action, summary string) error {
+	event := map[string]interface{}{
+		"routing_key":  routingKey,
+		"event_action": action,
+		"dedup_key":    incidentKey,
+	}
+	if action == "trigger" {
+		hostname, _ := os.Hostname()
+		event["payload"] = map[string]string{
+			"summary":  truncate("Spot data refresh failed: "+summary, 1024),
+			"source":   hostname,
+			"severity": "error",
+		}
+	}
+	return postIncident(pagerDutyEventsURL, "", event)
+}
+
+// postIncident posts a JSON body, authenticating with an Opsgenie key when given
+func postIncident(endpoint, genieKey string, body interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if genieKey != "" {
+		req.Header.Set("Authorization", "GenieKey "+genieKey)
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s: %s", resp.Status, msg)
+	}
+	return nil
+}
+
+// truncate shortens s to at most n characters, as incident tools cap
+// summary lengths, cutting between runes so the result stays valid UTF-8
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n-1]) + "…"
+}
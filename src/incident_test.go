@@ -0,0 +1,64 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{"short", 10, "short"},
+		{"exactly10!", 10, "exactly10!"},
+		{"a longer message", 8, "a longe…"},
+		{"région échouée", 8, "région …"},
+		{"東京リージョンの取得に失敗", 5, "東京リー…"},
+	}
+	for _, test := range tests {
+		got := truncate(test.s, test.n)
+		if got != test.want || !utf8.ValidString(got) || utf8.RuneCountInString(got) > test.n {
+			t.Errorf("truncate(%q, %d) = %q, want %q", test.s, test.n, got, test.want)
+		}
+	}
+}
+
+func TestResolveIncident(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer server.Close()
+	setVar(t, &pagerDutyEventsURL, server.URL)
+	t.Setenv("PAGERDUTY_ROUTING_KEY", "test")
+	t.Setenv("OPSGENIE_API_KEY", "")
+	setFlag(t, "incident-file", filepath.Join(t.TempDir(), "incident.json"))
+
+	// Successful runs without an open incident leave the incident tools alone
+	resolveIncident()
+	if requests != 0 {
+		t.Fatalf("resolved an incident that was never opened")
+	}
+
+	if err := recordIncident("no region could be fetched"); err != nil {
+		t.Fatal(err)
+	}
+	resolveIncident()
+	if requests != 1 {
+		t.Errorf("got %d resolve requests, want 1", requests)
+	}
+	if _, err := os.Stat(*incidentFile); !os.IsNotExist(err) {
+		t.Errorf("incident record left after resolving: %v", err)
+	}
+
+	resolveIncident()
+	if requests != 1 {
+		t.Errorf("resolved the same incident twice")
+	}
+}
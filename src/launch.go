@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+)
+
+var (
+	launchFlags        = flag.NewFlagSet("launch", flag.ExitOnError)
+	launchInstanceType = launchFlags.String("instance-type", "", "instance type to launch (e.g. c6g.4xlarge)")
+	launchRegion       = launchFlags.String("region", "", "AWS region to launch in (e.g. eu-west-1)")
+	launchAMI          = launchFlags.String("ami", "<AMI_ID>", "AMI to launch; left as a placeholder by default")
+	launchMaxPrice     = launchFlags.String("max-price", "", "optional maximum spot price in USD per hour (defaults to the on-demand price)")
+	launchFormat       = launchFlags.String("format", "aws-cli", "snippet format: aws-cli or terraform")
+)
+
+// runLaunch implements the launch command: it prints an aws-cli or Terraform
+// snippet requesting a spot instance, annotated with the current price
+func runLaunch(args []string) {
+	launchFlags.Parse(args)
+
+	if *launchInstanceType == "" || *launchRegion == "" {
+		fmt.Fprintln(os.Stderr, "usage: launch --instance-type <type> --region <region> [--ami <id>] [--max-price <usd>] [--format aws-cli|terraform]")
+		launchFlags.PrintDefaults()
+		os.Exit(2)
+	}
+	if *launchMaxPrice != "" {
+		if _, err := strconv.ParseFloat(*launchMaxPrice, 64); err != nil {
+			log.Fatalf("Invalid --max-price %q: %v", *launchMaxPrice, err)
+		}
+	}
+
+	deals, err := getSpotDeals(*launchRegion)
+	if err != nil {
+		log.Fatalf("Error getting spot deals for region %s: %v", *launchRegion, err)
+	}
+
+	priceContext := fmt.Sprintf("%s is not among the current high-savings deals in %s", *launchInstanceType, *launchRegion)
+	for _, instance := range deals {
+		if instance.InstanceType == *launchInstanceType {
+			price, _ := strconv.ParseFloat(instance.SpotPrice, 64)
+			priceContext = fmt.Sprintf("Current spot price of %s in %s: $%s/hour, $%.6f per vCPU, %s below on-demand",
+				instance.InstanceType, *launchRegion, instance.SpotPrice, price/float64(instance.VCPUS), instance.SpotSavingRate)
+			break
+		}
+	}
+
+	switch *launchFormat {
+	case "aws-cli":
+		fmt.Print(awsCLILaunchSnippet(priceContext))
+	case "terraform":
+		fmt.Print(terraformLaunchSnippet(priceContext))
+	default:
+		log.Fatalf("Unsupported format %q", *launchFormat)
+	}
+}
+
+// awsCLILaunchSnippet renders an aws ec2 run-instances command using the spot market
+func awsCLILaunchSnippet(priceContext string) string {
+	spotOptions := map[string]string{
+		"SpotInstanceType":             "one-time",
+		"InstanceInterruptionBehavior": "terminate",
+	}
+	if *launchMaxPrice != "" {
+		spotOptions["MaxPrice"] = *launchMaxPrice
+	}
+	options, _ := json.Marshal(map[string]interface{}{
+		"MarketType":  "spot",
+		"SpotOptions": spotOptions,
+	})
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n", priceContext)
+	b.WriteString("aws ec2 run-instances \\\n")
+	fmt.Fprintf(&b, "  --region %s \\\n", *launchRegion)
+	fmt.Fprintf(&b, "  --instance-type %s \\\n", *launchInstanceType)
+	fmt.Fprintf(&b, "  --image-id %s \\\n", *launchAMI)
+	fmt.Fprintf(&b, "  --instance-market-options '%s'\n", options)
+	return b.String()
+}
+
+// terraformLaunchSnippet renders an aws_instance resource using the spot market
+func terraformLaunchSnippet(priceContext string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n", priceContext)
+	fmt.Fprintf(&b, "provider \"aws\" {\n  region = %q\n}\n\n", *launchRegion)
+	b.WriteString("resource \"aws_instance\" \"spot\" {\n")
+	fmt.Fprintf(&b, "  ami           = %q\n", *launchAMI)
+	fmt.Fprintf(&b, "  instance_type = %q\n\n", *launchInstanceType)
+	b.WriteString("  instance_market_options {\n")
+	b.WriteString("    market_type = \"spot\"\n")
+	b.WriteString("    spot_options {\n")
+	b.WriteString("      instance_interruption_behavior = \"terminate\"\n")
+	if *launchMaxPrice != "" {
+		fmt.Fprintf(&b, "      max_price                      = %q\n", *launchMaxPrice)
+	}
+	b.WriteString("    }\n")
+	b.WriteString("  }\n")
+	b.WriteString("}\n")
+	return b.String()
+}
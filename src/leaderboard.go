@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"time"
+)
+
+var (
+	leaderboardFile    = flag.String("leaderboard", "", "also write the regions' global ranking over time, such as days holding the #1 deal, to this file")
+	leaderboardHistory = flag.String("leaderboard-history", "docs/region_ranks.jsonl", "ranking of the regions appended on every run, which the leaderboard is computed from")
+)
+
+// RankSnapshot is the order of the regions by their best deal in one run
+type RankSnapshot struct {
+	Time    string   `json:"time"`
+	Regions []string `json:"regions"` // best first
+}
+
+// LeaderboardEntry is a region's standing over the recorded runs
+type LeaderboardEntry struct {
+	Region string `json:"region"`
+	// DaysAtTop counts the days on which the region held the #1 deal at
+	// the last run of the day
+	DaysAtTop   int     `json:"days_at_top"`
+	AverageRank float64 `json:"average_rank"`
+	BestRank    int     `json:"best_rank"`
+	CurrentRank int     `json:"current_rank,omitempty"` // zero when absent from the last run
+}
+
+// Leaderboard ranks the regions by how often they held the best global deal
+type Leaderboard struct {
+	LastUpdated string             `json:"last_updated"`
+	Since       string             `json:"since"` // first recorded run
+	Days        int                `json:"days"`  // days with at least one run
+	Regions     []LeaderboardEntry `json:"regions"`
+}
+
+// rankRegions orders the regions by their best deal, with the strategy
+// that ranks the global top deals. Only regions fetched by the run are
+// ranked; stale ones, carried over with their last known prices, would
+// otherwise keep a rank their current prices may no longer earn.
+func rankRegions(data SpotData) RankSnapshot {
+	var deals []GlobalDeal
+	for region, instances := range freshRegions(data).Regions {
+		if len(instances) > 0 {
+			deals = append(deals, bestDeal(region, instances))
+		}
+	}
+	sort.Slice(deals, func(i, j int) bool {
+		if globalStrategy.Less(deals[i].instance(), deals[j].instance()) {
+			return true
+		}
+		if globalStrategy.Less(deals[j].instance(), deals[i].instance()) {
+			return false
+		}
+		return deals[i].Region < deals[j].Region
+	})
+
+	snapshot := RankSnapshot{Time: data.LastUpdated, Regions: make([]string, len(deals))}
+	for i, deal := range deals {
+		snapshot.Regions[i] = deal.Region
+	}
+	return snapshot
+}
+
+// appendRanks records a run's ranking in the history file
+func appendRanks(filename string, snapshot RankSnapshot) error {
+	line, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// readRanks reads the recorded rankings, oldest first
+func readRanks(filename string) ([]RankSnapshot, error) {
+	file, err := os.Open(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var snapshots []RankSnapshot
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		var snapshot RankSnapshot
+		if err := json.Unmarshal(scanner.Bytes(), &snapshot); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", filename, line, err)
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots, scanner.Err()
+}
+
+// leaderboard summarizes the recorded rankings
+func leaderboard(snapshots []RankSnapshot) Leaderboard {
+	var board Leaderboard
+	type standing struct {
+		ranks, rankSum, best, current int
+		days                          map[string]bool
+	}
+	standings := map[string]*standing{}
+	leaders := map[string]string{} // the #1 region of each day's last run
+	var current []string
+
+	for _, snapshot := range snapshots {
+		t, err := time.Parse(time.RFC3339, snapshot.Time)
+		if err != nil || len(snapshot.Regions) == 0 {
+			continue
+		}
+		if board.Since == "" {
+			board.Since = snapshot.Time
+		}
+		board.LastUpdated, current = snapshot.Time, snapshot.Regions
+		leaders[t.UTC().Format("2006-01-02")] = snapshot.Regions[0]
+
+		for i, region := range snapshot.Regions {
+			s := standings[region]
+			if s == nil {
+				s = &standing{best: i + 1}
+				standings[region] = s
+			}
+			s.ranks++
+			s.rankSum += i + 1
+			if i+1 < s.best {
+				s.best = i + 1
+			}
+		}
+	}
+	board.Days = len(leaders)
+
+	for i, region := range current {
+		standings[region].current = i + 1
+	}
+	daysAtTop := map[string]int{}
+	for _, region := range leaders {
+		daysAtTop[region]++
+	}
+
+	for region, s := range standings {
+		board.Regions = append(board.Regions, LeaderboardEntry{
+			Region:      region,
+			DaysAtTop:   daysAtTop[region],
+			AverageRank: math.Round(float64(s.rankSum)/float64(s.ranks)*100) / 100,
+			BestRank:    s.best,
+			CurrentRank: s.current,
+		})
+	}
+	sort.Slice(board.Regions, func(i, j int) bool {
+		a, b := board.Regions[i], board.Regions[j]
+		if a.DaysAtTop != b.DaysAtTop {
+			return a.DaysAtTop > b.DaysAtTop
+		}
+		if a.AverageRank != b.AverageRank {
+			return a.AverageRank < b.AverageRank
+		}
+		return a.Region < b.Region
+	})
+	return board
+}
+
+// updateLeaderboard records the run's ranking and rewrites the leaderboard
+func updateLeaderboard(filename, historyFile string, data SpotData) error {
+	if err := appendRanks(historyFile, rankRegions(data)); err != nil {
+		return err
+	}
+	snapshots, err := readRanks(historyFile)
+	if err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(leaderboard(snapshots), "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeJSONFile(filename, content)
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRankRegionsSkipsStaleRegions(t *testing.T) {
+	data := readFixture(t)
+	want := rankRegions(data).Regions
+
+	// A region the run failed to fetch keeps its old, cheaper prices
+	stale := "ap-south-1"
+	data.RegionsUpdated[stale] = "2023-12-01T00:00:00Z"
+	data.Regions[stale] = []Instance{{InstanceType: "c6g.8xlarge", VCPUS: 32, Memory: "64 GiB", SpotPrice: "0.0010"}}
+
+	got := rankRegions(data).Regions
+	var fresh []string
+	for _, region := range want {
+		if region != stale {
+			fresh = append(fresh, region)
+		}
+	}
+	if !reflect.DeepEqual(got, fresh) {
+		t.Errorf("rankRegions = %v, want %v", got, fresh)
+	}
+}
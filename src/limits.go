@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+// Limits guarding against malformed or malicious upstream responses
+const (
+	maxResponseBytes      = 32 << 20 // per upstream HTTP response
+	maxInstancesPerRegion = 2000
+	maxFieldLength        = 64 // instance type, memory, price and savings strings
+)
+
+// limitedReader fails with a descriptive error once more than limit bytes are read,
+// unlike io.LimitReader which silently truncates
+type limitedReader struct {
+	r         io.Reader
+	limit     int64
+	remaining int64
+}
+
+// newLimitedReader wraps r so reading past limit bytes returns an error
+func newLimitedReader(r io.Reader, limit int64) io.Reader {
+	return &limitedReader{r: r, limit: limit, remaining: limit}
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.remaining <= 0 {
+		// Only fail if the body really continues past the limit
+		var probe [1]byte
+		n, err := l.r.Read(probe[:])
+		if n > 0 {
+			return 0, fmt.Errorf("response exceeds the %d byte limit", l.limit)
+		}
+		return 0, err
+	}
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	return n, err
+}
+
+// readLimited reads r to the end, failing once more than limit bytes arrive
+func readLimited(r io.Reader, limit int64) ([]byte, error) {
+	return io.ReadAll(newLimitedReader(r, limit))
+}
+
+// validateInstances rejects instance lists that exceed the size limits
+func validateInstances(instances []Instance) error {
+	if len(instances) > maxInstancesPerRegion {
+		return fmt.Errorf("%d instances exceed the limit of %d per region", len(instances), maxInstancesPerRegion)
+	}
+	for i, instance := range instances {
+		if err := validateInstance(i, instance); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateInstance rejects an instance whose string fields exceed maxFieldLength
+func validateInstance(i int, instance Instance) error {
+	for name, value := range map[string]string{
+		"InstanceType":       instance.InstanceType,
+		"Memory":             instance.Memory,
+		"SpotSavingRate":     instance.SpotSavingRate,
+		"UpstreamSavingRate": instance.UpstreamSavingRate,
+		"SpotPrice":          instance.SpotPrice,
+		"SpotPriceRange":     instance.SpotPriceRange,
+		"OnDemandPrice":      instance.OnDemandPrice,
+		"InterruptionRate":   instance.InterruptionRate,
+		"Category":           instance.Category,
+	} {
+		if len(value) > maxFieldLength {
+			return fmt.Errorf("instance %d: %s is %d bytes, over the %d byte limit", i, name, len(value), maxFieldLength)
+		}
+	}
+	return nil
+}
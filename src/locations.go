@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+var locationsCache = flag.String("locations-cache", filepath.Join(os.TempDir(), "ec2-spot-finder-locations.json"), "keep the last AWS locations list fetched here, used when the live one fails; empty disables it")
+
+// bundledLocations is a snapshot of the AWS locations list, the last resort
+// when neither the live list nor a cached copy is available
+//
+//go:embed locations.json
+var bundledLocations []byte
+
+// fetchRegions retrieves the list of AWS regions. A failure of the
+// locations endpoint falls back to the cached copy, then to the bundled
+// snapshot, so it does not fail the whole run.
+func fetchRegions() ([]string, error) {
+	regions, err := fetchLiveRegions()
+	if err == nil {
+		return regions, nil
+	}
+
+	if *locationsCache != "" {
+		content, cacheErr := os.ReadFile(*locationsCache)
+		if cacheErr == nil {
+			if regions, cacheErr = parseRegions(content); cacheErr == nil {
+				log.Printf("Error fetching the AWS locations list, using the copy cached in %s: %v", *locationsCache, err)
+				return regions, nil
+			}
+		}
+	}
+
+	log.Printf("Error fetching the AWS locations list, using the bundled snapshot: %v", err)
+	return parseRegions(bundledLocations)
+}
+
+// fetchLiveRegions fetches the locations list and caches it once it parses
+func fetchLiveRegions() ([]string, error) {
+	resp, err := http.Get(locationsURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUpstreamUnavailable, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: unexpected status %s", ErrUpstreamUnavailable, resp.Status)
+	}
+
+	content, err := io.ReadAll(newLimitedReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUpstreamUnavailable, err)
+	}
+	regions, err := parseRegions(content)
+	if err != nil {
+		return nil, err
+	}
+
+	if *locationsCache != "" {
+		if err := os.WriteFile(*locationsCache, content, 0644); err != nil {
+			log.Printf("Error caching the AWS locations list: %v", err)
+		}
+	}
+	return regions, nil
+}
+
+// parseRegions extracts the sorted codes of the AWS Regions in a locations list
+func parseRegions(content []byte) ([]string, error) {
+	regions, err := decodeRegions(bytes.NewReader(content), func(region Region) bool {
+		return region.Type == "AWS Region"
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDecode, err)
+	}
+	if len(regions) == 0 {
+		return nil, fmt.Errorf("%w: no AWS Region listed", ErrValidation)
+	}
+
+	var regionCodes []string
+	for _, region := range regions {
+		regionCodes = append(regionCodes, region.Code)
+	}
+
+	// Sort region codes alphabetically
+	sort.Strings(regionCodes)
+
+	return regionCodes, nil
+}
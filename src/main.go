@@ -0,0 +1,122 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+)
+
+// main runs a refresh, or the subcommand named by the first argument. The
+// WebAssembly build of the query engine replaces this file with wasm/query.go.
+//
+//go:generate sh wasm/build.sh ../docs
+func main() {
+	if err := loadConfig(configPath()); err != nil {
+		log.Fatalf("Error loading config: %v", err)
+	}
+
+	// Dispatch subcommands; without one, refresh the published dataset
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "check":
+			runCheck(os.Args[2:])
+			return
+		case "watch":
+			runWatch(os.Args[2:])
+			return
+		case "launch":
+			runLaunch(os.Args[2:])
+			return
+		case "combo":
+			runCombo(os.Args[2:])
+			return
+		case "ci-runners":
+			runCIRunners(os.Args[2:])
+			return
+		case "gpu":
+			runGPU(os.Args[2:])
+			return
+		case "simulate":
+			runSimulate(os.Args[2:])
+			return
+		case "serve":
+			runServe(os.Args[2:])
+			return
+		case "tui":
+			runTUI(os.Args[2:])
+			return
+		case "completion":
+			runCompletion(os.Args[2:])
+			return
+		case "version":
+			runVersion(os.Args[2:])
+			return
+		}
+	}
+
+	flag.Parse()
+
+	if *envName != "" {
+		if err := applyEnvironment(*envName, flag.CommandLine); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Using the %s environment", *envName)
+	}
+
+	merge, err := mergeFunc(*mergeMode)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := fixClock(*fixedNow, *reproducible); err != nil {
+		log.Fatal(err)
+	}
+
+	if *chaosSpec != "" {
+		if err := enableChaos(*chaosSpec); err != nil {
+			log.Fatalf("Error enabling chaos mode: %v", err)
+		}
+	}
+
+	// Count the run's upstream traffic for the heartbeat
+	runStats = measureRun()
+
+	// Cache hits are not upstream traffic, but still inputs of the provenance
+	if *cacheDir != "" {
+		if err := enableCache(*cacheDir, *cacheTTL, config.CacheTTLs); err != nil {
+			log.Fatalf("Error opening cache directory: %v", err)
+		}
+	}
+
+	// Hash every upstream response for the provenance statement
+	var inputs *recordingTransport
+	if *provenanceFile != "" {
+		inputs = recordInputs()
+	}
+
+	if *profile != "" {
+		stop, err := startProfiling(*profile)
+		if err != nil {
+			log.Fatalf("Error starting profiling: %v", err)
+		}
+		stopProfiling = stop
+		defer stopProfiling()
+	}
+
+	// Resume the regions an interrupted run already fetched
+	if *checkpointFile != "" {
+		var err error
+		if checkpoint, err = openCheckpoint(*checkpointFile, *checkpointMaxAge); err != nil {
+			log.Printf("Error opening checkpoint, fetching every region: %v", err)
+		}
+	}
+
+	if err := refresh(merge, inputs); err != nil {
+		failRun(exitCode(err), "Error refreshing spot data: %v", err)
+	}
+
+	// Only runs that get through resolve an incident opened by an earlier
+	// failure and drop the checkpoint
+	resolveIncident()
+	checkpoint.Remove()
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files under testdata/golden with the current output")
+
+// testNow is the time every test runs at
+var testNow = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	// The defaults, without the config of the working directory
+	if err := loadConfig(filepath.Join("testdata", "config.json")); err != nil {
+		log.Fatal(err)
+	}
+	clock = func() time.Time { return testNow }
+	os.Exit(m.Run())
+}
+
+// checkGolden compares got with the golden file testdata/golden/name, or
+// rewrites the file when the tests run with -update
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", "golden", name)
+	if *updateGolden {
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v; run the tests with -update to create it", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s differs from %s; run the tests with -update if the change is intended:\n%s", name, path, got)
+	}
+}
+
+// readFixture loads the fixture dataset testdata/spot_data.json
+func readFixture(t testing.TB) SpotData {
+	t.Helper()
+	data, err := readExistingData(filepath.Join("testdata", "spot_data.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
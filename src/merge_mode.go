@@ -0,0 +1,89 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+)
+
+var mergeMode = flag.String("merge-mode", "merge", "how fetched data combines with the published dataset: replace, merge or append")
+
+// mergeModes combine the published dataset with freshly fetched data
+var mergeModes = map[string]func(existing, new SpotData) SpotData{
+	// replace publishes only the fresh snapshot, dropping instances and
+	// regions upstream no longer lists
+	"replace": func(existing, new SpotData) SpotData { return new },
+	// merge updates published instances in place and adds new ones
+	"merge": mergeSpotData,
+	// append never alters published instances and only adds new ones
+	"append": appendSpotData,
+}
+
+// mergeFunc returns the merge mode called name
+func mergeFunc(name string) (func(existing, new SpotData) SpotData, error) {
+	merge, ok := mergeModes[name]
+	if !ok {
+		var names []string
+		for name := range mergeModes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown merge mode %q (want one of %v)", name, names)
+	}
+	return merge, nil
+}
+
+// appendSpotData adds the regions and instance types missing from the
+// published dataset, leaving every published entry as it was. The global top
+// 5 is ranked again from the resulting regions so it matches what is listed.
+func appendSpotData(existing, new SpotData) SpotData {
+	merged := existing
+	merged.LastUpdated = new.LastUpdated
+
+	merged.Regions = make(map[string][]Instance, len(existing.Regions))
+	for region, instances := range existing.Regions {
+		merged.Regions[region] = instances
+	}
+	merged.Sources = make(map[string]string, len(existing.Sources))
+	for region, source := range existing.Sources {
+		merged.Sources[region] = source
+	}
+	merged.RegionsUpdated = mergeRegionLabels(existing.RegionsUpdated, new.RegionsUpdated)
+
+	for region, newInstances := range new.Regions {
+		published, ok := existing.Regions[region]
+		if !ok {
+			merged.Regions[region] = newInstances
+			if source, ok := new.Sources[region]; ok {
+				merged.Sources[region] = source
+			}
+			continue
+		}
+
+		seen := make(map[string]bool, len(published))
+		for _, instance := range published {
+			seen[canonicalInstance(instance).InstanceType] = true
+		}
+		appended := append([]Instance(nil), published...)
+		for _, instance := range newInstances {
+			instance = canonicalInstance(instance)
+			if !seen[instance.InstanceType] {
+				seen[instance.InstanceType] = true
+				appended = append(appended, instance)
+			}
+		}
+		merged.Regions[region] = appended
+	}
+
+	merged.Providers = mergeProviders(existing.Providers, new.Providers)
+
+	var deals []GlobalDeal
+	for region, instances := range merged.Regions {
+		if len(instances) > 0 {
+			deals = append(deals, regionDeals(region, instances)...)
+		}
+	}
+	merged.GlobalTop5 = topGlobalDeals(deals)
+
+	return merged
+}
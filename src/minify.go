@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"strings"
+)
+
+var minifiedJSON = flag.Bool("minified", true, "next to each published JSON file, also write a compact .min.json copy for the site to fetch")
+
+// minifiedName returns the name of the compact copy of a JSON file,
+// e.g. docs/spot_data.min.json for docs/spot_data.json
+func minifiedName(filename string) string {
+	return strings.TrimSuffix(filename, ".json") + ".min.json"
+}
+
+// writeJSONFile writes indented JSON content to filename, which keeps Git
+// diffs readable, and with --minified its compact copy, which the site
+// fetches since the indentation is a large share of the page weight
+func writeJSONFile(filename string, content []byte) error {
+	if err := os.WriteFile(filename, append(content, '\n'), 0644); err != nil {
+		return err
+	}
+	if !*minifiedJSON {
+		return nil
+	}
+
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, content); err != nil {
+		return err
+	}
+	return os.WriteFile(minifiedName(filename), append(compact.Bytes(), '\n'), 0644)
+}
+
+// writeMinifiedDataFile writes the compact copy of the dataset published
+// at filename, when --minified is set. The copy is replaced atomically,
+// like the serve daemon does for the dataset itself.
+func writeMinifiedDataFile(filename string, data SpotData) error {
+	if !*minifiedJSON {
+		return nil
+	}
+	name := minifiedName(filename)
+	if err := createDataFile(name+".tmp", data, writeMinifiedSpotData); err != nil {
+		return err
+	}
+	return os.Rename(name+".tmp", name)
+}
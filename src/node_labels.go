@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var nodeLabelsFile = flag.String("node-labels", "", "also write Kubernetes node labels per instance type to this file")
+
+// nodeLabelPrefix namespaces the labels Kubernetes does not define itself
+const nodeLabelPrefix = "spot-finder.fjcloud.io/"
+
+// spotPriceTiers name the thirds of the instance types ranked by their
+// cheapest price per vCPU
+var spotPriceTiers = []string{"low", "medium", "high"}
+
+// NodeLabels maps every published instance type to the labels a node of
+// that type would carry, for admission controllers and cost-allocation tools
+type NodeLabels struct {
+	LastUpdated   string                       `json:"last_updated"`
+	InstanceTypes map[string]map[string]string `json:"instance_types"`
+}
+
+// nodeLabels derives the labels of every instance type listed in any region.
+// The architecture uses the kubernetes.io/arch values, amd64 and arm64.
+func nodeLabels(data SpotData) NodeLabels {
+	// The cheapest price per vCPU of each type across regions
+	cheapest := map[string]float64{}
+	for _, instances := range data.Regions {
+		for _, instance := range instances {
+			price, err := strconv.ParseFloat(instance.SpotPrice, 64)
+			if err != nil || instance.VCPUS == 0 {
+				continue
+			}
+			perVCPU := price / float64(instance.VCPUS)
+			if current, ok := cheapest[instance.InstanceType]; !ok || perVCPU < current {
+				cheapest[instance.InstanceType] = perVCPU
+			}
+		}
+	}
+
+	types := make([]string, 0, len(cheapest))
+	for instanceType := range cheapest {
+		types = append(types, instanceType)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		if cheapest[types[i]] != cheapest[types[j]] {
+			return cheapest[types[i]] < cheapest[types[j]]
+		}
+		return types[i] < types[j]
+	})
+
+	labels := NodeLabels{LastUpdated: data.LastUpdated, InstanceTypes: make(map[string]map[string]string, len(types))}
+	for i, instanceType := range types {
+		arch := "amd64"
+		if instanceArchitecture(instanceType) == "arm64" {
+			arch = "arm64"
+		}
+		family, _, _ := strings.Cut(instanceType, ".")
+		category := classifyInstance("aws", instanceType)
+
+		typeLabels := map[string]string{
+			"kubernetes.io/arch":                arch,
+			"node.kubernetes.io/instance-type":  instanceType,
+			nodeLabelPrefix + "family":          family,
+			nodeLabelPrefix + "gpu":             strconv.FormatBool(category == categoryGPU),
+			nodeLabelPrefix + "spot-price-tier": spotPriceTiers[i*len(spotPriceTiers)/len(types)],
+		}
+		if category != "" {
+			typeLabels[nodeLabelPrefix+"category"] = category
+		}
+		labels.InstanceTypes[instanceType] = typeLabels
+	}
+	return labels
+}
+
+// writeNodeLabels writes the node labels of the dataset's instance types
+func writeNodeLabels(filename string, data SpotData) error {
+	content, err := json.MarshalIndent(nodeLabels(data), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, append(content, '\n'), 0644)
+}
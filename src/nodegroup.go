@@ -0,0 +1,59 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var comboNodeGroup = comboFlags.String("node-group", "<NODEGROUP_NAME>", "name of the EKS node group crossplane and ack manifests patch")
+
+// nodeGroupManifest renders a combo as a partial node group manifest to
+// merge over an existing resource, e.g. as a Kustomize patch. Every value
+// sits at path under spec, and the recommendation is recorded in
+// annotations so reviewers see its cost in the GitOps diff.
+func nodeGroupManifest(combo Combo, apiVersion, kind string, path []string) ([]byte, error) {
+	// An EKS node group runs a single AMI type and so a single architecture
+	arch := ""
+	var types []string
+	for _, pick := range combo.Picks {
+		pickArch := instanceArchitecture(pick.Instance.InstanceType)
+		if arch != "" && pickArch != arch {
+			return nil, errors.New("the recommended types mix architectures, choose one with --arch")
+		}
+		arch = pickArch
+		types = append(types, pick.Instance.InstanceType)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "apiVersion: %s\n", apiVersion)
+	fmt.Fprintf(&b, "kind: %s\n", kind)
+	b.WriteString("metadata:\n")
+	fmt.Fprintf(&b, "  name: %q\n", *comboNodeGroup)
+	b.WriteString("  annotations:\n")
+	fmt.Fprintf(&b, "    %srecommended-instance-types: %q\n", nodeLabelPrefix, strings.Join(types, ","))
+	fmt.Fprintf(&b, "    %srecommended-vcpus: \"%d\"\n", nodeLabelPrefix, combo.VCPUS)
+	fmt.Fprintf(&b, "    %shourly-cost: \"%.4f\"\n", nodeLabelPrefix, combo.HourlyCost)
+	b.WriteString("spec:\n")
+	indent := "  "
+	for _, key := range path {
+		fmt.Fprintf(&b, "%s%s:\n", indent, key)
+		indent += "  "
+	}
+	fmt.Fprintf(&b, "%scapacityType: SPOT\n", indent)
+	fmt.Fprintf(&b, "%sinstanceTypes:\n", indent)
+	for _, instanceType := range types {
+		fmt.Fprintf(&b, "%s  - %s\n", indent, instanceType)
+	}
+	return []byte(strings.TrimSuffix(b.String(), "\n")), nil
+}
+
+// crossplaneNodeGroup renders a combo as a patch of an Upbound AWS provider NodeGroup
+func crossplaneNodeGroup(combo Combo) ([]byte, error) {
+	return nodeGroupManifest(combo, "eks.aws.upbound.io/v1beta1", "NodeGroup", []string{"forProvider"})
+}
+
+// ackNodegroup renders a combo as a patch of an AWS Controllers for Kubernetes Nodegroup
+func ackNodegroup(combo Combo) ([]byte, error) {
+	return nodeGroupManifest(combo, "eks.services.k8s.aws/v1alpha1", "Nodegroup", nil)
+}
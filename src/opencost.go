@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"flag"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+var openCostFile = flag.String("opencost-csv", "", "also write the spot prices as an OpenCost custom pricing CSV to this file")
+
+// encodeOpenCostCSV renders the regional spot prices in the custom pricing
+// CSV read by OpenCost's CSV provider (CSV_PATH). Rows leave InstanceID
+// empty so they price every node by its region and instance type labels.
+// Bucketed prices are published at their midpoint.
+func encodeOpenCostCSV(data SpotData) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+
+	if err := w.Write([]string{"EndTimestamp", "InstanceID", "Region", "AssetClass", "InstanceIDField", "InstanceType", "MarketPriceHourly", "Version"}); err != nil {
+		return nil, err
+	}
+
+	end := data.LastUpdated
+	if t, err := time.Parse(time.RFC3339, data.LastUpdated); err == nil {
+		end = t.UTC().Format("2006-01-02 15:04:05 UTC")
+	}
+
+	regions := make([]string, 0, len(data.Regions))
+	for region := range data.Regions {
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+
+	for _, region := range regions {
+		for _, instance := range data.Regions[region] {
+			if _, err := strconv.ParseFloat(instance.SpotPrice, 64); err != nil {
+				continue
+			}
+			record := []string{end, "", region, "node", "metadata.name", instance.InstanceType, instance.SpotPrice, ""}
+			if err := w.Write(record); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// writeOpenCostCSV writes the OpenCost custom pricing CSV of the dataset
+func writeOpenCostCSV(filename string, data SpotData) error {
+	content, err := encodeOpenCostCSV(data)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, content, 0644)
+}
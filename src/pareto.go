@@ -0,0 +1,66 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+)
+
+// ParetoDeal is an instance on the efficient frontier, with its region
+type ParetoDeal struct {
+	Region string `json:"region"`
+	Instance
+}
+
+// paretoFrontier returns the instances no other instance beats on every
+// criterion at once: price (lower), vCPUs and memory (higher) and
+// interruption rate (lower). Each is the best available trade-off for some
+// weighting of the criteria, which a single sort order cannot show.
+func paretoFrontier(regions map[string][]Instance) []ParetoDeal {
+	var candidates []ParetoDeal
+	for region, instances := range regions {
+		for _, instance := range instances {
+			if _, err := strconv.ParseFloat(instance.SpotPrice, 64); err == nil {
+				candidates = append(candidates, ParetoDeal{region, instance})
+			}
+		}
+	}
+
+	var frontier []ParetoDeal
+	for i, candidate := range candidates {
+		dominated := false
+		for j, other := range candidates {
+			if i != j && dominates(other.Instance, candidate.Instance) {
+				dominated = true
+				break
+			}
+		}
+		if !dominated {
+			frontier = append(frontier, candidate)
+		}
+	}
+
+	sort.Slice(frontier, func(i, j int) bool {
+		priceI, priceJ := parsePrice(frontier[i].SpotPrice), parsePrice(frontier[j].SpotPrice)
+		if priceI != priceJ {
+			return priceI < priceJ
+		}
+		if frontier[i].Region != frontier[j].Region {
+			return frontier[i].Region < frontier[j].Region
+		}
+		return frontier[i].InstanceType < frontier[j].InstanceType
+	})
+	return frontier
+}
+
+// dominates reports whether a is at least as good as b on every criterion
+// and strictly better on one. Unknown interruption rates count as the worst level.
+func dominates(a, b Instance) bool {
+	priceA, priceB := parsePrice(a.SpotPrice), parsePrice(b.SpotPrice)
+	memoryA, memoryB := parseLeadingNumber(a.Memory), parseLeadingNumber(b.Memory)
+	levelA, levelB := interruptionLevel(a.InterruptionRate), interruptionLevel(b.InterruptionRate)
+
+	if priceA > priceB || a.VCPUS < b.VCPUS || memoryA < memoryB || levelA > levelB {
+		return false
+	}
+	return priceA < priceB || a.VCPUS > b.VCPUS || memoryA > memoryB || levelA < levelB
+}
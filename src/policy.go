@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Policy is an organization's list of rules the published recommendations
+// must comply with. Instances breaking any rule are left out and reported.
+type Policy struct {
+	Rules []PolicyRule `json:"rules"`
+}
+
+// PolicyRule forbids instances by region, family or architecture pattern,
+// or above an interruption level. Empty criteria are ignored.
+type PolicyRule struct {
+	Name            string   `json:"name"`
+	Reason          string   `json:"reason"`
+	Regions         []string `json:"regions"`          // e.g. "cn-*"
+	Families        []string `json:"families"`         // e.g. "t2", "*n"
+	Architectures   []string `json:"architectures"`    // arm64 or x86_64
+	MaxInterruption string   `json:"max_interruption"` // e.g. "10-15%" forbids "15-20%" and ">20%"
+}
+
+// PolicyViolations reports the instances a rule excluded from the output
+type PolicyViolations struct {
+	Rule     string   `json:"rule"`
+	Reason   string   `json:"reason,omitempty"`
+	Excluded int      `json:"excluded"`
+	Examples []string `json:"examples"` // region/instance type
+}
+
+// maxPolicyExamples caps the examples reported per rule
+const maxPolicyExamples = 10
+
+var policy Policy
+
+// policyExclusions collects the region/instance type pairs excluded by each
+// rule during a run, deduplicated across fresh and merged data
+var policyExclusions = struct {
+	sync.Mutex
+	byRule map[string]map[string]bool
+}{byRule: make(map[string]map[string]bool)}
+
+// validate checks the rule patterns and levels when the policy is loaded
+func (p Policy) validate() error {
+	for i, rule := range p.Rules {
+		if rule.Name == "" {
+			return fmt.Errorf("rule %d has no name", i)
+		}
+		for _, pattern := range append(append([]string(nil), rule.Regions...), rule.Families...) {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("rule %s: invalid pattern %q: %w", rule.Name, pattern, err)
+			}
+		}
+		for _, architecture := range rule.Architectures {
+			if architecture != "arm64" && architecture != "x86_64" {
+				return fmt.Errorf("rule %s: unknown architecture %q (expected arm64 or x86_64)", rule.Name, architecture)
+			}
+		}
+		if rule.MaxInterruption != "" && interruptionLevel(rule.MaxInterruption) == len(interruptionLevels) {
+			return fmt.Errorf("rule %s: unknown interruption level %q (expected one of %s)", rule.Name, rule.MaxInterruption, strings.Join(interruptionLevels, ", "))
+		}
+	}
+	return nil
+}
+
+// ForbidsRegion reports whether a rule excludes every instance of a region,
+// so the region need not be fetched at all
+func (p Policy) ForbidsRegion(region string) bool {
+	for _, rule := range p.Rules {
+		if matchesAny(rule.Regions, region) {
+			return true
+		}
+	}
+	return false
+}
+
+// violation returns the first rule an instance breaks, or nil
+func (p Policy) violation(region string, instance Instance) *PolicyRule {
+	family, _, _ := strings.Cut(instance.InstanceType, ".")
+	for i, rule := range p.Rules {
+		switch {
+		case matchesAny(rule.Regions, region),
+			matchesAny(rule.Families, family),
+			matchesAny(rule.Architectures, instanceArchitecture(instance.InstanceType)),
+			rule.MaxInterruption != "" && instance.InterruptionRate != "" &&
+				interruptionLevel(instance.InterruptionRate) > interruptionLevel(rule.MaxInterruption):
+			return &p.Rules[i]
+		}
+	}
+	return nil
+}
+
+// Filter returns the instances of a region complying with the policy,
+// recording the excluded ones
+func (p Policy) Filter(region string, instances []Instance) []Instance {
+	if len(p.Rules) == 0 {
+		return instances
+	}
+	kept := make([]Instance, 0, len(instances))
+	for _, instance := range instances {
+		if rule := p.violation(region, instance); rule != nil {
+			recordPolicyExclusion(rule.Name, region+"/"+instance.InstanceType)
+			continue
+		}
+		kept = append(kept, instance)
+	}
+	return kept
+}
+
+// recordPolicyExclusion notes an instance excluded by a rule
+func recordPolicyExclusion(rule, key string) {
+	policyExclusions.Lock()
+	defer policyExclusions.Unlock()
+	if policyExclusions.byRule[rule] == nil {
+		policyExclusions.byRule[rule] = make(map[string]bool)
+	}
+	policyExclusions.byRule[rule][key] = true
+}
+
+// enforcePolicy removes non-compliant instances and top deals left from
+// earlier runs, then reports everything the policy excluded during this run
+func enforcePolicy(data *SpotData) {
+	if len(policy.Rules) == 0 {
+		return
+	}
+
+	regions := make(map[string][]Instance, len(data.Regions))
+	for region, instances := range data.Regions {
+		if kept := policy.Filter(region, instances); len(kept) > 0 {
+			regions[region] = kept
+		}
+	}
+	data.Regions = regions
+
+	var top []GlobalDeal
+	for _, deal := range data.GlobalTop5 {
+		if rule := policy.violation(deal.Region, deal.instance()); rule != nil {
+			recordPolicyExclusion(rule.Name, deal.Region+"/"+deal.InstanceType)
+			continue
+		}
+		top = append(top, deal)
+	}
+	data.GlobalTop5 = top
+
+	data.PolicyViolations = nil
+	policyExclusions.Lock()
+	defer policyExclusions.Unlock()
+	for _, rule := range policy.Rules {
+		excluded := policyExclusions.byRule[rule.Name]
+		if len(excluded) == 0 {
+			continue
+		}
+		examples := make([]string, 0, len(excluded))
+		for key := range excluded {
+			examples = append(examples, key)
+		}
+		sort.Strings(examples)
+		if len(examples) > maxPolicyExamples {
+			examples = examples[:maxPolicyExamples]
+		}
+		log.Printf("Policy rule %s excluded %d instances", rule.Name, len(excluded))
+		data.PolicyViolations = append(data.PolicyViolations, PolicyViolations{rule.Name, rule.Reason, len(excluded), examples})
+	}
+}
+
+// matchesAny reports whether value matches one of the glob patterns
+func matchesAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, value); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// instanceArchitecture infers the CPU architecture of an EC2 instance type:
+// Graviton families carry a "g" among the attributes after the generation
+// digit (c6g, m7gd, is4gen), plus the a1 and Apple silicon mac families
+func instanceArchitecture(instanceType string) string {
+	family, _, _ := strings.Cut(strings.ToLower(instanceType), ".")
+	switch {
+	case family == "a1":
+		return "arm64"
+	case strings.HasPrefix(family, "mac"):
+		if family == "mac1" {
+			return "x86_64"
+		}
+		return "arm64"
+	}
+	i := letterPrefix(family)
+	for i < len(family) && unicode.IsDigit(rune(family[i])) {
+		i++
+	}
+	if strings.Contains(family[i:], "g") {
+		return "arm64"
+	}
+	return "x86_64"
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// priceBucket returns the bounds of the width-sized range holding price
+func priceBucket(price, width float64) (low, high float64) {
+	// Nudge the quotient so prices on a boundary, e.g. 0.29 for 0.01, are not
+	// pushed into the range below by floating-point error
+	low = math.Floor(price/width+1e-9) * width
+	return low, low + width
+}
+
+// bucketPrice replaces an exact price with the midpoint of its range and
+// returns the range label, e.g. "0.0400-0.0500"
+func bucketPrice(price, width float64) (float64, string) {
+	low, high := priceBucket(price, width)
+	return (low + high) / 2, fmt.Sprintf("%.4f-%.4f", low, high)
+}
+
+// bucketSpotPrices hides the exact spot prices of freshly fetched data for
+// public publishing: every price becomes the midpoint of its width-sized
+// range and the range itself is published next to it. Values the exact
+// price could be worked back from, such as savings rates, are derived from
+// the midpoint or dropped. History, diffs and rankings downstream only ever
+// see the bucketed values.
+func bucketSpotPrices(data *SpotData, width float64) {
+	if width <= 0 {
+		return
+	}
+	bucketRegions(data.Regions, width)
+	for _, provider := range data.Providers {
+		bucketRegions(provider.Regions, width)
+	}
+	for i, deal := range data.GlobalTop5 {
+		midpoint, label := bucketPrice(deal.SpotPrice, width)
+		data.GlobalTop5[i].SpotPrice = midpoint
+		data.GlobalTop5[i].PriceRange = label
+		data.GlobalTop5[i].PricePerVCPU = midpoint / float64(deal.VCPUS)
+		data.GlobalTop5[i].OnDemandPrice = 0
+	}
+}
+
+// bucketRegions buckets the spot price of every instance in place. With the
+// on-demand price published, the savings rate is recomputed from the
+// midpoint, as the upstream or exact rate would give the exact price away.
+func bucketRegions(regions map[string][]Instance, width float64) {
+	for _, instances := range regions {
+		for i, instance := range instances {
+			price, err := strconv.ParseFloat(instance.SpotPrice, 64)
+			if err != nil {
+				continue
+			}
+			midpoint, label := bucketPrice(price, width)
+			instances[i].SpotPrice = strconv.FormatFloat(midpoint, 'f', 4, 64)
+			instances[i].SpotPriceRange = label
+			instances[i].UpstreamSavingRate = ""
+			if onDemand := parsePrice(instance.OnDemandPrice); onDemand > 0 {
+				instances[i].SpotSavingRate = fmt.Sprintf("%.0f%%", savingRate(midpoint, onDemand))
+			}
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// TestBucketSpotPricesWithholdsExactPrices checks that instances whose
+// exact prices share a bucket publish identical data, so nothing published
+// tells the exact price
+func TestBucketSpotPricesWithholdsExactPrices(t *testing.T) {
+	publish := func(exact, upstreamRate string) ([]byte, []byte) {
+		// As decoded from ec2.shop, whose rate is computed from the exact price
+		instance := recomputeSavingRate(Instance{
+			InstanceType:   "c6g.4xlarge",
+			VCPUS:          16,
+			Memory:         "32 GiB",
+			SpotSavingRate: upstreamRate,
+			SpotPrice:      exact,
+			OnDemandPrice:  "0.5440",
+		})
+		data := SpotData{
+			Regions:    map[string][]Instance{"eu-west-1": {instance}},
+			GlobalTop5: []GlobalDeal{newGlobalDeal("eu-west-1", instance)},
+		}
+		bucketSpotPrices(&data, 0.01)
+
+		regions, err := json.Marshal(data.Regions)
+		if err != nil {
+			t.Fatal(err)
+		}
+		top, err := json.Marshal(data.GlobalTop5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return regions, top
+	}
+
+	lowRegions, lowTop := publish("0.2001", "63%")
+	highRegions, highTop := publish("0.2099", "55%")
+	if string(lowRegions) != string(highRegions) {
+		t.Errorf("regions differ within a bucket:\n%s\n%s", lowRegions, highRegions)
+	}
+	if string(lowTop) != string(highTop) {
+		t.Errorf("global top 5 differs within a bucket:\n%s\n%s", lowTop, highTop)
+	}
+
+	want := `{"eu-west-1":[{"InstanceType":"c6g.4xlarge","VCPUS":16,"Memory":"32 GiB","SpotSavingRate":"62%","SpotPrice":"0.2050","SpotPriceRange":"0.2000-0.2100","OnDemandPrice":"0.5440"}]}`
+	if string(lowRegions) != want {
+		t.Errorf("got %s, want %s", lowRegions, want)
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"log"
+	"os"
+	"runtime"
+	"runtime/pprof"
+)
+
+// stopProfiling stops the profiling started by --profile; failRun calls it
+// too, as deferred calls do not run when the process exits with os.Exit
+var stopProfiling = func() {}
+
+// startProfiling writes a CPU profile to <prefix>.cpu.pprof until the
+// returned function is called, which also writes a heap profile to
+// <prefix>.heap.pprof; inspect them with go tool pprof
+func startProfiling(prefix string) (func(), error) {
+	cpuFile, err := os.Create(prefix + ".cpu.pprof")
+	if err != nil {
+		return nil, err
+	}
+	if err := pprof.StartCPUProfile(cpuFile); err != nil {
+		cpuFile.Close()
+		return nil, err
+	}
+
+	return func() {
+		pprof.StopCPUProfile()
+		cpuFile.Close()
+
+		heapFile, err := os.Create(prefix + ".heap.pprof")
+		if err != nil {
+			log.Printf("Error creating heap profile: %v", err)
+			return
+		}
+		defer heapFile.Close()
+
+		// Collect garbage first so the profile reflects live memory
+		runtime.GC()
+		if err := pprof.WriteHeapProfile(heapFile); err != nil {
+			log.Printf("Error writing heap profile: %v", err)
+		}
+		log.Printf("Wrote %s.cpu.pprof and %s.heap.pprof", prefix, prefix)
+	}, nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+)
+
+// profileName restricts profile names to safe file names
+var profileName = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*$`)
+
+// validateProfiles applies query defaults to the configured profiles and
+// checks them when the config is loaded
+func validateProfiles(profiles map[string]Query) error {
+	for name, query := range profiles {
+		if !profileName.MatchString(name) {
+			return fmt.Errorf("profile %q: names may only contain lowercase letters, digits, dots, dashes and underscores", name)
+		}
+		if query.Limit == 0 {
+			query.Limit = defaultQueryLimit
+		}
+		if err := query.validate(); err != nil {
+			return fmt.Errorf("profile %s: %w", name, err)
+		}
+		profiles[name] = query
+	}
+	return nil
+}
+
+// writeProfiles writes each profile's top list to <dir>/<name>.json, so a
+// single run serves several teams with different requirements
+func writeProfiles(dir string, profiles map[string]Query, data SpotData) error {
+	if len(profiles) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		content, err := json.MarshalIndent(profiles[name].respond(data), "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := writeJSONFile(filepath.Join(dir, name+".json"), content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
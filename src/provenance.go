@@ -0,0 +1,194 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var provenanceFile = flag.String("provenance", "docs/provenance.json", "write an in-toto/SLSA provenance statement for the data file here; empty disables it")
+
+// Provenance statement types, following in-toto attestations and SLSA v1
+const (
+	inTotoStatementType  = "https://in-toto.io/Statement/v1"
+	slsaProvenanceType   = "https://slsa.dev/provenance/v1"
+	provenanceBuildType  = "https://github.com/fjcloud/ec2-spot-finder-static/refresh@v1"
+	dssePayloadType      = "application/vnd.in-toto+json"
+	provenanceSigningKey = "PROVENANCE_SIGNING_KEY" // base64 Ed25519 seed
+)
+
+// ResourceDescriptor identifies an artifact or input by URI or name and digest
+type ResourceDescriptor struct {
+	Name   string            `json:"name,omitempty"`
+	URI    string            `json:"uri,omitempty"`
+	Digest map[string]string `json:"digest"`
+}
+
+// ProvenanceStatement records how a snapshot was derived: the upstream
+// responses it was built from and the tool that built it
+type ProvenanceStatement struct {
+	Type          string               `json:"_type"`
+	Subject       []ResourceDescriptor `json:"subject"`
+	PredicateType string               `json:"predicateType"`
+	Predicate     struct {
+		BuildDefinition struct {
+			BuildType            string               `json:"buildType"`
+			ExternalParameters   map[string]string    `json:"externalParameters"`
+			ResolvedDependencies []ResourceDescriptor `json:"resolvedDependencies"`
+		} `json:"buildDefinition"`
+		RunDetails struct {
+			Builder struct {
+				ID      string            `json:"id"`
+				Version map[string]string `json:"version"`
+			} `json:"builder"`
+			Metadata struct {
+				InvocationID string `json:"invocationId,omitempty"`
+				StartedOn    string `json:"startedOn"`
+				FinishedOn   string `json:"finishedOn"`
+			} `json:"metadata"`
+		} `json:"runDetails"`
+	} `json:"predicate"`
+}
+
+// recordingTransport hashes the body of every upstream GET response so the
+// provenance can name the exact inputs of a snapshot
+type recordingTransport struct {
+	next    http.RoundTripper
+	started time.Time
+
+	mu     sync.Mutex
+	inputs map[string]string // URL to sha256 of the full body
+}
+
+// recordInputs starts recording upstream responses made through the default transport
+func recordInputs() *recordingTransport {
+	t := &recordingTransport{next: http.DefaultTransport, started: clock().UTC(), inputs: make(map[string]string)}
+	http.DefaultTransport = t
+	return t
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || req.Method != http.MethodGet {
+		return resp, err
+	}
+	resp.Body = &hashingBody{ReadCloser: resp.Body, hash: sha256.New(), done: func(sum string) {
+		t.mu.Lock()
+		t.inputs[req.URL.String()] = sum
+		t.mu.Unlock()
+	}}
+	return resp, nil
+}
+
+// hashingBody hashes a response body as it is read. Decoders may stop
+// before the end, so the rest is drained into the hash on Close.
+type hashingBody struct {
+	io.ReadCloser
+	hash hash.Hash
+	done func(sum string)
+}
+
+func (b *hashingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.hash.Write(p[:n])
+	return n, err
+}
+
+func (b *hashingBody) Close() error {
+	io.Copy(b.hash, io.LimitReader(b.ReadCloser, maxResponseBytes))
+	b.done(hex.EncodeToString(b.hash.Sum(nil)))
+	return b.ReadCloser.Close()
+}
+
+// writeProvenance writes the statement for the data file and, when
+// PROVENANCE_SIGNING_KEY is set, a DSSE envelope signing it next to it
+func writeProvenance(filename, dataFilename string, inputs *recordingTransport) error {
+	content, err := os.ReadFile(dataFilename)
+	if err != nil {
+		return err
+	}
+	digest := sha256.Sum256(content)
+
+	var statement ProvenanceStatement
+	statement.Type = inTotoStatementType
+	statement.PredicateType = slsaProvenanceType
+	statement.Subject = []ResourceDescriptor{{Name: dataFilename, Digest: map[string]string{"sha256": hex.EncodeToString(digest[:])}}}
+
+	definition := &statement.Predicate.BuildDefinition
+	definition.BuildType = provenanceBuildType
+	definition.ExternalParameters = map[string]string{"args": strings.Join(os.Args[1:], " "), "config": configPath()}
+	inputs.mu.Lock()
+	for uri, sum := range inputs.inputs {
+		definition.ResolvedDependencies = append(definition.ResolvedDependencies, ResourceDescriptor{URI: uri, Digest: map[string]string{"sha256": sum}})
+	}
+	inputs.mu.Unlock()
+	sort.Slice(definition.ResolvedDependencies, func(i, j int) bool {
+		return definition.ResolvedDependencies[i].URI < definition.ResolvedDependencies[j].URI
+	})
+
+	run := &statement.Predicate.RunDetails
+	info := buildInfo()
+	if info.Commit == "unknown" && os.Getenv("GITHUB_SHA") != "" {
+		info.Commit = os.Getenv("GITHUB_SHA")
+	}
+	run.Builder.ID = programName
+	run.Builder.Version = map[string]string{"version": info.Version, "commit": info.Commit, "go": info.GoVersion}
+	if server, repo, id := os.Getenv("GITHUB_SERVER_URL"), os.Getenv("GITHUB_REPOSITORY"), os.Getenv("GITHUB_RUN_ID"); repo != "" && id != "" {
+		run.Builder.ID = fmt.Sprintf("%s/%s/actions/runs/%s", server, repo, id)
+		run.Metadata.InvocationID = id
+	}
+	run.Metadata.StartedOn = inputs.started.Format(time.RFC3339)
+	run.Metadata.FinishedOn = clock().UTC().Format(time.RFC3339)
+
+	payload, err := json.MarshalIndent(statement, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filename, append(payload, '\n'), 0644); err != nil {
+		return err
+	}
+
+	if seed := os.Getenv(provenanceSigningKey); seed != "" {
+		return writeSignedProvenance(strings.TrimSuffix(filename, ".json")+".dsse.json", payload, seed)
+	}
+	return nil
+}
+
+// writeSignedProvenance writes a DSSE envelope signing the statement with an Ed25519 key
+func writeSignedProvenance(filename string, payload []byte, encodedSeed string) error {
+	seed, err := base64.StdEncoding.DecodeString(encodedSeed)
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return errors.New(provenanceSigningKey + " must be a base64-encoded 32-byte Ed25519 seed")
+	}
+	key := ed25519.NewKeyFromSeed(seed)
+	keyID := sha256.Sum256(key.Public().(ed25519.PublicKey))
+
+	// DSSE signs the pre-authentication encoding of the type and payload
+	pae := fmt.Sprintf("DSSEv1 %d %s %d %s", len(dssePayloadType), dssePayloadType, len(payload), payload)
+	envelope := map[string]interface{}{
+		"payloadType": dssePayloadType,
+		"payload":     base64.StdEncoding.EncodeToString(payload),
+		"signatures": []map[string]string{{
+			"keyid": hex.EncodeToString(keyID[:]),
+			"sig":   base64.StdEncoding.EncodeToString(ed25519.Sign(key, []byte(pae))),
+		}},
+	}
+	content, err := json.MarshalIndent(envelope, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, append(content, '\n'), 0644)
+}
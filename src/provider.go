@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Provider fetches the current spot deals of one cloud
+type Provider interface {
+	Name() string
+	Fetch(ctx context.Context) (SpotData, error)
+}
+
+// providers lists the clouds that can be enabled in the config file
+var providers = map[string]Provider{
+	"aws": awsProvider{},
+}
+
+// providerNamed looks up an enabled provider by its config name
+func providerNamed(name string) (Provider, error) {
+	provider, ok := providers[name]
+	if !ok {
+		var names []string
+		for name := range providers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown provider %q (expected one of %s)", name, strings.Join(names, ", "))
+	}
+	return provider, nil
+}
+
+// awsProvider fetches EC2 spot deals from ec2.shop, falling back to the AWS spot price feed
+type awsProvider struct{}
+
+func (awsProvider) Name() string {
+	return "aws"
+}
+
+func (awsProvider) Fetch(ctx context.Context) (SpotData, error) {
+	return fetchSpotData()
+}
+
+// ProviderResult is the outcome of one provider's fetch
+type ProviderResult struct {
+	Provider string
+	Data     SpotData
+	Err      error
+	Duration time.Duration
+}
+
+// fetchProviders fetches every provider concurrently. Each gets its own
+// timeout and a failure, timeout or panic only affects that provider's
+// result, so a slow cloud neither delays nor fails the others.
+func fetchProviders(enabled []Provider, timeout time.Duration) map[string]ProviderResult {
+	results := make(map[string]ProviderResult, len(enabled))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, provider := range enabled {
+		wg.Add(1)
+		go func(provider Provider) {
+			defer wg.Done()
+			start := time.Now()
+			data, err := fetchWithTimeout(provider, timeout)
+			result := ProviderResult{provider.Name(), data, err, time.Since(start)}
+			if err != nil {
+				log.Printf("Error fetching spot data from %s after %s: %v", provider.Name(), result.Duration.Round(time.Millisecond), err)
+			}
+			mu.Lock()
+			results[provider.Name()] = result
+			mu.Unlock()
+		}(provider)
+	}
+
+	wg.Wait()
+	return results
+}
+
+// fetchWithTimeout runs a provider's fetch, abandoning it when the timeout
+// expires even if the provider does not observe its context
+func fetchWithTimeout(provider Provider, timeout time.Duration) (SpotData, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	type outcome struct {
+		data SpotData
+		err  error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- outcome{err: fmt.Errorf("provider panicked: %v", r)}
+			}
+		}()
+		data, err := provider.Fetch(ctx)
+		done <- outcome{data, err}
+	}()
+
+	select {
+	case o := <-done:
+		return o.data, o.err
+	case <-ctx.Done():
+		return SpotData{}, fmt.Errorf("%w: timed out after %s", ErrUpstreamUnavailable, timeout)
+	}
+}
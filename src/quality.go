@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+var qualityFile = flag.String("quality", "", "also write a data quality report of the run, such as failed regions and suspect prices, to this file")
+
+// maxQualityIssues caps the issues listed per kind; the counts stay exact
+const maxQualityIssues = 50
+
+// QualityReport summarizes the quality of a run's AWS data, so silent
+// degradation such as a region that stopped parsing shows up
+type QualityReport struct {
+	GeneratedAt string `json:"generated_at"`
+	LastUpdated string `json:"last_updated,omitempty"`
+	// Healthy is true when every expected region was fetched
+	Healthy            bool              `json:"healthy"`
+	Error              string            `json:"error,omitempty"` // why the fetch failed as a whole
+	RegionsExpected    int               `json:"regions_expected"`
+	RegionsFetched     int               `json:"regions_fetched"`
+	RegionsFallback    []string          `json:"regions_fallback,omitempty"` // served from the AWS spot price feed
+	FailedRegions      map[string]string `json:"failed_regions,omitempty"`
+	ParseFailures      int               `json:"parse_failures"`      // responses that could not be decoded
+	ValidationFailures int               `json:"validation_failures"` // responses rejected by the sanity checks
+	Instances          int               `json:"instances"`
+	SuspectPriceCount  int               `json:"suspect_price_count"`
+	SuspectPrices      []QualityIssue    `json:"suspect_prices,omitempty"`
+	SchemaWarningCount int               `json:"schema_warning_count"`
+	SchemaWarnings     []QualityIssue    `json:"schema_warnings,omitempty"`
+	// SavingsMismatches are upstream savings rates the prices do not support
+	SavingsMismatchCount int            `json:"savings_mismatch_count"`
+	SavingsMismatches    []QualityIssue `json:"savings_mismatches,omitempty"`
+}
+
+// QualityIssue is a problem found with an instance's published fields
+type QualityIssue struct {
+	Region       string `json:"region"`
+	InstanceType string `json:"instanceType"`
+	Problem      string `json:"problem"`
+}
+
+// FetchOutcome is what a fetch of every region saw, beyond the data itself
+type FetchOutcome struct {
+	Expected int
+	Failed   map[string]error
+	Fallback []string
+}
+
+// lastFetch holds the outcome of the latest fetchSpotData. The provider may
+// still be running after a timeout, hence the lock.
+var lastFetch struct {
+	sync.Mutex
+	outcome FetchOutcome
+}
+
+// recordFetchOutcome keeps the outcome of a fetch for the quality report
+func recordFetchOutcome(outcome FetchOutcome) {
+	lastFetch.Lock()
+	defer lastFetch.Unlock()
+	lastFetch.outcome = outcome
+}
+
+// qualityReport assesses the AWS result of a run
+func qualityReport(data SpotData, fetchErr error) QualityReport {
+	lastFetch.Lock()
+	outcome := lastFetch.outcome
+	lastFetch.Unlock()
+
+	report := QualityReport{
+		GeneratedAt:     clock().UTC().Format(time.RFC3339),
+		LastUpdated:     data.LastUpdated,
+		RegionsExpected: outcome.Expected,
+		RegionsFetched:  len(data.Regions),
+		RegionsFallback: outcome.Fallback,
+	}
+	if fetchErr != nil {
+		report.Error = fetchErr.Error()
+	}
+	for region, err := range outcome.Failed {
+		if report.FailedRegions == nil {
+			report.FailedRegions = make(map[string]string)
+		}
+		report.FailedRegions[region] = err.Error()
+		switch {
+		case errors.Is(err, ErrValidation):
+			report.ValidationFailures++
+		case errors.Is(err, ErrDecode):
+			report.ParseFailures++
+		}
+	}
+	report.Healthy = fetchErr == nil && len(outcome.Failed) == 0 && report.RegionsFetched >= report.RegionsExpected
+
+	regions := make([]string, 0, len(data.Regions))
+	for region := range data.Regions {
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+	for _, region := range regions {
+		instances := data.Regions[region]
+		report.Instances += len(instances)
+		median := medianPricePerVCPU(instances)
+		for _, instance := range instances {
+			for _, problem := range suspectPrice(instance, median) {
+				report.SuspectPriceCount++
+				if len(report.SuspectPrices) < maxQualityIssues {
+					report.SuspectPrices = append(report.SuspectPrices, QualityIssue{region, instance.InstanceType, problem})
+				}
+			}
+			for _, problem := range schemaWarnings(instance) {
+				report.SchemaWarningCount++
+				if len(report.SchemaWarnings) < maxQualityIssues {
+					report.SchemaWarnings = append(report.SchemaWarnings, QualityIssue{region, instance.InstanceType, problem})
+				}
+			}
+			if instance.UpstreamSavingRate != "" {
+				report.SavingsMismatchCount++
+				if len(report.SavingsMismatches) < maxQualityIssues {
+					problem := fmt.Sprintf("ec2.shop reports a savings rate of %q, the prices give %s", instance.UpstreamSavingRate, instance.SpotSavingRate)
+					report.SavingsMismatches = append(report.SavingsMismatches, QualityIssue{region, instance.InstanceType, problem})
+				}
+			}
+		}
+	}
+	return report
+}
+
+// medianPricePerVCPU returns the median price per vCPU of a region's instances
+func medianPricePerVCPU(instances []Instance) float64 {
+	var prices []float64
+	for _, instance := range instances {
+		if price := parsePrice(instance.SpotPrice); price > 0 && instance.VCPUS > 0 {
+			prices = append(prices, price/float64(instance.VCPUS))
+		}
+	}
+	if len(prices) == 0 {
+		return 0
+	}
+	sort.Float64s(prices)
+	return prices[len(prices)/2]
+}
+
+// suspectPrice lists what makes an instance's spot price implausible
+func suspectPrice(instance Instance, median float64) []string {
+	if instance.SpotPriceRange != "" {
+		return nil
+	}
+	price := parsePrice(instance.SpotPrice)
+	if price <= 0 {
+		return []string{fmt.Sprintf("spot price %q is not a positive number", instance.SpotPrice)}
+	}
+
+	var problems []string
+	if onDemand := parsePrice(instance.OnDemandPrice); onDemand > 0 && price > onDemand {
+		problems = append(problems, fmt.Sprintf("spot price %s is above the on-demand price %s", instance.SpotPrice, instance.OnDemandPrice))
+	}
+	if instance.VCPUS > 0 && median > 0 && price/float64(instance.VCPUS) < median/10 {
+		problems = append(problems, "price per vCPU is below a tenth of the region's median")
+	}
+	return problems
+}
+
+// schemaWarnings lists the fields an instance lacks or has malformed
+func schemaWarnings(instance Instance) []string {
+	var warnings []string
+	if instance.VCPUS <= 0 {
+		warnings = append(warnings, "VCPUS is missing")
+	}
+	if parseLeadingNumber(instance.Memory) <= 0 {
+		warnings = append(warnings, fmt.Sprintf("Memory %q is not a size", instance.Memory))
+	}
+	if instance.SpotSavingRate == "" {
+		warnings = append(warnings, "SpotSavingRate is missing")
+	}
+	if instance.OnDemandPrice == "" {
+		warnings = append(warnings, "OnDemandPrice is missing")
+	}
+	return warnings
+}
+
+// writeQualityReport writes the quality report of a run's AWS result
+func writeQualityReport(filename string, data SpotData, fetchErr error) error {
+	content, err := json.MarshalIndent(qualityReport(data, fetchErr), "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeJSONFile(filename, content)
+}
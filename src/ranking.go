@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Strategy orders instances for a ranked output section
+type Strategy interface {
+	// Less reports whether a ranks above b
+	Less(a, b Instance) bool
+}
+
+// strategies maps the names accepted in the config file to ranking strategies
+var strategies = map[string]Strategy{
+	"cheapest_per_vcpu":   CheapestPerVCPU{},
+	"cheapest_per_gb":     CheapestPerGB{},
+	"best_score":          BestScore{},
+	"lowest_interruption": LowestInterruption{},
+}
+
+// strategyNamed looks up a ranking strategy by its config name
+func strategyNamed(name string) (Strategy, error) {
+	strategy, ok := strategies[name]
+	if !ok {
+		var names []string
+		for name := range strategies {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown ranking %q (expected one of %s)", name, strings.Join(names, ", "))
+	}
+	return strategy, nil
+}
+
+// rankInstances sorts instances best first, keeping the upstream order of ties
+func rankInstances(instances []Instance, strategy Strategy) {
+	sort.SliceStable(instances, func(i, j int) bool {
+		return strategy.Less(instances[i], instances[j])
+	})
+}
+
+// CheapestPerVCPU ranks by spot price per vCPU, the site's historical order
+type CheapestPerVCPU struct{}
+
+func (CheapestPerVCPU) Less(a, b Instance) bool {
+	return pricePerVCPU(a) < pricePerVCPU(b)
+}
+
+// CheapestPerGB ranks by spot price per GiB of memory, for memory-bound workloads
+type CheapestPerGB struct{}
+
+func (CheapestPerGB) Less(a, b Instance) bool {
+	return pricePerGB(a) < pricePerGB(b)
+}
+
+// LowestInterruption ranks by interruption frequency, then by price per vCPU.
+// Instances without interruption data rank last.
+type LowestInterruption struct{}
+
+func (LowestInterruption) Less(a, b Instance) bool {
+	levelA, levelB := interruptionLevel(a.InterruptionRate), interruptionLevel(b.InterruptionRate)
+	if levelA != levelB {
+		return levelA < levelB
+	}
+	return pricePerVCPU(a) < pricePerVCPU(b)
+}
+
+// BestScore ranks by the effective price per vCPU once interruptions are
+// accounted for: a cheap instance that is often reclaimed delivers fewer
+// useful hours than a slightly pricier stable one
+type BestScore struct{}
+
+func (BestScore) Less(a, b Instance) bool {
+	return bestScore(a) < bestScore(b)
+}
+
+// bestScore divides the price per vCPU by the expected share of time the
+// instance keeps running; unknown interruption rates assume the worst level
+func bestScore(instance Instance) float64 {
+	midpoint := interruptionMidpoints[len(interruptionMidpoints)-1]
+	if level := interruptionLevel(instance.InterruptionRate); level < len(interruptionMidpoints) {
+		midpoint = interruptionMidpoints[level]
+	}
+	return pricePerVCPU(instance) / (1 - midpoint/100)
+}
+
+// pricePerVCPU returns the spot price per vCPU, or +Inf when it is unknown
+func pricePerVCPU(instance Instance) float64 {
+	price, err := strconv.ParseFloat(instance.SpotPrice, 64)
+	if err != nil || instance.VCPUS == 0 {
+		return math.Inf(1)
+	}
+	return price / float64(instance.VCPUS)
+}
+
+// pricePerGB returns the spot price per GiB of memory, or +Inf when it is unknown
+func pricePerGB(instance Instance) float64 {
+	price, err := strconv.ParseFloat(instance.SpotPrice, 64)
+	memory := parseLeadingNumber(instance.Memory)
+	if err != nil || memory == 0 {
+		return math.Inf(1)
+	}
+	return price / memory
+}
+
+// parsePrice parses a price string, treating malformed and non-finite
+// values as zero
+func parsePrice(s string) float64 {
+	price, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(price) || math.IsInf(price, 0) {
+		return 0
+	}
+	return price
+}
+
+// parseLeadingNumber parses the number at the start of values such as
+// "32 GiB" or "67%", returning zero when there is none
+func parseLeadingNumber(s string) float64 {
+	s = strings.TrimSpace(s)
+	end := 0
+	for end < len(s) && (s[end] == '.' || (s[end] >= '0' && s[end] <= '9')) {
+		end++
+	}
+	number, _ := strconv.ParseFloat(s[:end], 64)
+	return number
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"flag"
+	"sort"
+	"strings"
+)
+
+var maxPerRegion = flag.Int("max-per-region", 0, "publish at most this many instances per region, sampled fairly across families; 0 publishes all")
+
+// capInstances keeps at most n instances. Families take turns giving up
+// their best remaining instance, best family first, so a family with many
+// sizes cannot crowd out the others. Kept instances stay in their order.
+func capInstances(instances []Instance, n int, strategy Strategy) []Instance {
+	if n <= 0 || len(instances) <= n {
+		return instances
+	}
+
+	ranked := make([]int, len(instances))
+	for i := range ranked {
+		ranked[i] = i
+	}
+	sort.SliceStable(ranked, func(a, b int) bool {
+		return strategy.Less(instances[ranked[a]], instances[ranked[b]])
+	})
+
+	// Queue each family's instances best first, in the order of the families' best
+	var families []string
+	queues := map[string][]int{}
+	for _, i := range ranked {
+		family, _, _ := strings.Cut(instances[i].InstanceType, ".")
+		if _, ok := queues[family]; !ok {
+			families = append(families, family)
+		}
+		queues[family] = append(queues[family], i)
+	}
+
+	keep := make([]bool, len(instances))
+	for kept := 0; kept < n; {
+		for _, family := range families {
+			if queue := queues[family]; kept < n && len(queue) > 0 {
+				keep[queue[0]] = true
+				queues[family] = queue[1:]
+				kept++
+			}
+		}
+	}
+
+	capped := make([]Instance, 0, n)
+	for i, instance := range instances {
+		if keep[i] {
+			capped = append(capped, instance)
+		}
+	}
+	return capped
+}
+
+// capRegions applies --max-per-region to every region of data
+func capRegions(data *SpotData) {
+	for region, instances := range data.Regions {
+		data.Regions[region] = capInstances(instances, *maxPerRegion, regionStrategy)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// RunStats is a refresh's own resource usage, recorded in the heartbeat so
+// upstream slowdowns and growth show up over months of scheduled runs
+type RunStats struct {
+	Requests        int64   `json:"requests"`
+	FailedRequests  int64   `json:"failed_requests"` // transport errors and non-2xx responses
+	BytesDownloaded int64   `json:"bytes_downloaded"`
+	WallTimeSeconds float64 `json:"wall_time_seconds"`
+}
+
+// statsTransport counts the upstream requests of a run and the bytes
+// their response bodies deliver
+type statsTransport struct {
+	next    http.RoundTripper
+	started time.Time
+
+	requests atomic.Int64
+	failed   atomic.Int64
+	bytes    atomic.Int64
+}
+
+// runStats measures the current refresh; nil outside of one
+var runStats *statsTransport
+
+// measureRun starts counting requests made through the default transport.
+// It must wrap the transport before recordInputs, so bytes the provenance
+// hashing drains on Close are counted too.
+func measureRun() *statsTransport {
+	t := &statsTransport{next: http.DefaultTransport, started: time.Now()}
+	http.DefaultTransport = t
+	return t
+}
+
+func (t *statsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.requests.Add(1)
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		t.failed.Add(1)
+		return resp, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		t.failed.Add(1)
+	}
+	resp.Body = &countingBody{ReadCloser: resp.Body, bytes: &t.bytes}
+	return resp, nil
+}
+
+// Stats reports the usage so far, or nil when nothing is measured
+func (t *statsTransport) Stats() *RunStats {
+	if t == nil {
+		return nil
+	}
+	return &RunStats{
+		Requests:        t.requests.Load(),
+		FailedRequests:  t.failed.Load(),
+		BytesDownloaded: t.bytes.Load(),
+		WallTimeSeconds: time.Since(t.started).Round(time.Millisecond).Seconds(),
+	}
+}
+
+// countingBody adds the bytes read from a response body to a counter
+type countingBody struct {
+	io.ReadCloser
+	bytes *atomic.Int64
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.bytes.Add(int64(n))
+	return n, err
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// savingsTolerance is the gap, in percentage points, between the upstream
+// savings rate and the one computed from the prices that is put down to
+// rounding rather than reported as a discrepancy
+const savingsTolerance = 2
+
+// recomputeSavingRate replaces an instance's upstream savings rate with the
+// discount its spot price gives off its on-demand price, keeping the upstream
+// value in UpstreamSavingRate when the two disagree. Instances without both
+// prices keep the upstream rate.
+func recomputeSavingRate(instance Instance) Instance {
+	spot, onDemand := parsePrice(instance.SpotPrice), parsePrice(instance.OnDemandPrice)
+	if spot <= 0 || onDemand <= 0 {
+		return instance
+	}
+
+	computed := savingRate(spot, onDemand)
+	upstream := canonicalInstance(instance).SpotSavingRate
+	if rate := parseLeadingNumber(upstream); upstream == "" || math.Abs(rate-computed) > savingsTolerance {
+		instance.UpstreamSavingRate = upstream
+	}
+	instance.SpotSavingRate = fmt.Sprintf("%.0f%%", computed)
+	return instance
+}
+
+// savingRate is the discount, in whole percent, of a spot price off an
+// on-demand price
+func savingRate(spot, onDemand float64) float64 {
+	return math.Round((1 - spot/onDemand) * 100)
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Schedule restricts when the serve daemon refreshes and sends
+// notifications. Times are read in the schedule's timezone, so windows
+// follow daylight saving changes.
+type Schedule struct {
+	Timezone string `json:"timezone"` // IANA name such as "Europe/Paris"; the server's local time by default
+	// Windows are the periods refreshes run in, each at its own interval.
+	// The first window containing the current time applies; outside all of
+	// them nothing is fetched. Without windows, refreshes always run.
+	Windows []TimeWindow `json:"windows"`
+	// QuietHours are periods in which notifications are held back
+	QuietHours []TimeWindow `json:"quiet_hours"`
+
+	location *time.Location
+}
+
+// TimeWindow is a daily period on some weekdays. A window whose end comes
+// before its start runs past midnight and belongs to the day it starts on.
+type TimeWindow struct {
+	Days     []string `json:"days"` // "mon" to "sun"; every day when empty
+	From     string   `json:"from"` // "HH:MM", midnight by default
+	To       string   `json:"to"`   // "HH:MM", the end of the day by default
+	Interval duration `json:"interval"`
+
+	from, to int // minutes since midnight
+}
+
+// weekdays maps the day names used in schedules to their weekday
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// validate resolves the timezone and parses every window
+func (s *Schedule) validate() error {
+	s.location = time.Local
+	if s.Timezone != "" {
+		location, err := time.LoadLocation(s.Timezone)
+		if err != nil {
+			return fmt.Errorf("timezone: %w", err)
+		}
+		s.location = location
+	}
+	for i := range s.Windows {
+		if err := s.Windows[i].parse(); err != nil {
+			return fmt.Errorf("windows[%d]: %w", i, err)
+		}
+		if s.Windows[i].Interval <= 0 {
+			return fmt.Errorf("windows[%d]: an interval is required", i)
+		}
+	}
+	for i := range s.QuietHours {
+		if err := s.QuietHours[i].parse(); err != nil {
+			return fmt.Errorf("quiet_hours[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// parse checks the window's days and converts its bounds to minutes
+func (w *TimeWindow) parse() error {
+	for _, day := range w.Days {
+		if _, ok := weekdays[strings.ToLower(day)]; !ok {
+			return fmt.Errorf("unknown day %q (expected mon, tue, wed, thu, fri, sat or sun)", day)
+		}
+	}
+	var err error
+	if w.from, err = parseClock(w.From, 0); err != nil {
+		return err
+	}
+	w.to, err = parseClock(w.To, 24*60)
+	return err
+}
+
+// parseClock converts "HH:MM" to minutes since midnight, accepting "24:00"
+// as the end of the day
+func parseClock(clock string, fallback int) (int, error) {
+	if clock == "" {
+		return fallback, nil
+	}
+	if clock == "24:00" {
+		return 24 * 60, nil
+	}
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q (expected HH:MM)", clock)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// onDay reports whether the window runs on weekday
+func (w TimeWindow) onDay(day time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, name := range w.Days {
+		if weekdays[strings.ToLower(name)] == day {
+			return true
+		}
+	}
+	return false
+}
+
+// contains reports whether the local time t falls within the window
+func (w TimeWindow) contains(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	if w.from < w.to {
+		return w.onDay(t.Weekday()) && minute >= w.from && minute < w.to
+	}
+	// Overnight windows continue into the next day's early hours
+	return (w.onDay(t.Weekday()) && minute >= w.from) || (w.onDay(t.AddDate(0, 0, -1).Weekday()) && minute < w.to)
+}
+
+// refreshInterval returns the interval of the window containing now, or
+// fallback without windows. It reports false outside every window.
+func (s Schedule) refreshInterval(now time.Time, fallback time.Duration) (time.Duration, bool) {
+	if len(s.Windows) == 0 {
+		return fallback, true
+	}
+	local := now.In(s.location)
+	for _, window := range s.Windows {
+		if window.contains(local) {
+			return time.Duration(window.Interval), true
+		}
+	}
+	return 0, false
+}
+
+// quiet reports whether notifications are held back at now
+func (s Schedule) quiet(now time.Time) bool {
+	local := now.In(s.location)
+	for _, window := range s.QuietHours {
+		if window.contains(local) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// scoreVariables are the instance attributes a scoring formula may use
+var scoreVariables = map[string]func(Instance) float64{
+	"price":          func(i Instance) float64 { return parsePrice(i.SpotPrice) },
+	"on_demand":      func(i Instance) float64 { return parsePrice(i.OnDemandPrice) },
+	"price_per_vcpu": pricePerVCPU,
+	"price_per_gb":   pricePerGB,
+	"vcpus":          func(i Instance) float64 { return float64(i.VCPUS) },
+	"memory":         func(i Instance) float64 { return parseLeadingNumber(i.Memory) },
+	"savings":        func(i Instance) float64 { return parseLeadingNumber(i.SpotSavingRate) },
+	"interruption":   interruptionPercent,
+	"volatility":     func(i Instance) float64 { return priceVolatility[i.InstanceType] },
+}
+
+// priceVolatility is the coefficient of variation of each instance type's
+// spot price over the history window, averaged across regions. It is loaded
+// before a refresh when the scoring formula uses it.
+var priceVolatility map[string]float64
+
+// interruptionPercent estimates an instance's interruption frequency in
+// percent; unknown rates assume the worst level
+func interruptionPercent(instance Instance) float64 {
+	if level := interruptionLevel(instance.InterruptionRate); level < len(interruptionMidpoints) {
+		return interruptionMidpoints[level]
+	}
+	return interruptionMidpoints[len(interruptionMidpoints)-1]
+}
+
+// ScoreFormula ranks by a formula from the config file, lowest score first
+type ScoreFormula struct {
+	eval      func(Instance) float64
+	variables map[string]bool
+}
+
+func (f ScoreFormula) Less(a, b Instance) bool {
+	return f.score(a) < f.score(b)
+}
+
+// score evaluates the formula; results that are not a number rank last
+func (f ScoreFormula) score(instance Instance) float64 {
+	score := f.eval(instance)
+	if math.IsNaN(score) {
+		return math.Inf(1)
+	}
+	return score
+}
+
+// Uses reports whether the formula reads a variable
+func (f ScoreFormula) Uses(name string) bool {
+	return f.variables[name]
+}
+
+// parseScoreFormula compiles a formula such as
+// "score = 0.6*price_per_vcpu + 0.3*interruption + 0.1*volatility". It
+// supports numbers, the scoreVariables, + - * /, unary minus and parentheses.
+func parseScoreFormula(source string) (ScoreFormula, error) {
+	expr := strings.TrimSpace(source)
+	if name, rest, ok := strings.Cut(expr, "="); ok && strings.TrimSpace(name) == "score" {
+		expr = rest
+	}
+	p := &formulaParser{input: expr, variables: map[string]bool{}}
+	eval, err := p.sum()
+	if err == nil && p.peek() != 0 {
+		err = fmt.Errorf("unexpected %q at offset %d", p.input[p.pos], p.pos)
+	}
+	if err != nil {
+		return ScoreFormula{}, fmt.Errorf("scoring: %w", err)
+	}
+	return ScoreFormula{eval: eval, variables: p.variables}, nil
+}
+
+// formulaParser is a recursive descent parser turning a formula into a
+// closure over an instance
+type formulaParser struct {
+	input     string
+	pos       int
+	variables map[string]bool
+}
+
+// peek skips spaces and returns the next byte, or 0 at the end
+func (p *formulaParser) peek() byte {
+	for p.pos < len(p.input) && p.input[p.pos] == ' ' {
+		p.pos++
+	}
+	if p.pos == len(p.input) {
+		return 0
+	}
+	return p.input[p.pos]
+}
+
+// sum parses terms separated by + and -
+func (p *formulaParser) sum() (func(Instance) float64, error) {
+	left, err := p.product()
+	if err != nil {
+		return nil, err
+	}
+	for op := p.peek(); op == '+' || op == '-'; op = p.peek() {
+		p.pos++
+		right, err := p.product()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		if op == '+' {
+			left = func(i Instance) float64 { return l(i) + right(i) }
+		} else {
+			left = func(i Instance) float64 { return l(i) - right(i) }
+		}
+	}
+	return left, nil
+}
+
+// product parses factors separated by * and /
+func (p *formulaParser) product() (func(Instance) float64, error) {
+	left, err := p.factor()
+	if err != nil {
+		return nil, err
+	}
+	for op := p.peek(); op == '*' || op == '/'; op = p.peek() {
+		p.pos++
+		right, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		if op == '*' {
+			left = func(i Instance) float64 { return l(i) * right(i) }
+		} else {
+			left = func(i Instance) float64 { return l(i) / right(i) }
+		}
+	}
+	return left, nil
+}
+
+// factor parses a number, a variable, a negation or a parenthesized sum
+func (p *formulaParser) factor() (func(Instance) float64, error) {
+	c := p.peek()
+	switch {
+	case c == 0:
+		return nil, fmt.Errorf("unexpected end of formula")
+	case c == '-':
+		p.pos++
+		operand, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+		return func(i Instance) float64 { return -operand(i) }, nil
+	case c == '(':
+		p.pos++
+		inner, err := p.sum()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ')' {
+			return nil, fmt.Errorf("missing ) at offset %d", p.pos)
+		}
+		p.pos++
+		return inner, nil
+	case c == '.' || (c >= '0' && c <= '9'):
+		start := p.pos
+		for p.pos < len(p.input) && (p.input[p.pos] == '.' || (p.input[p.pos] >= '0' && p.input[p.pos] <= '9')) {
+			p.pos++
+		}
+		value, err := strconv.ParseFloat(p.input[start:p.pos], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", p.input[start:p.pos])
+		}
+		return func(Instance) float64 { return value }, nil
+	case c == '_' || (c >= 'a' && c <= 'z'):
+		start := p.pos
+		for p.pos < len(p.input) && (p.input[p.pos] == '_' || (p.input[p.pos] >= 'a' && p.input[p.pos] <= 'z')) {
+			p.pos++
+		}
+		name := p.input[start:p.pos]
+		variable, ok := scoreVariables[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown variable %q", name)
+		}
+		p.variables[name] = true
+		return variable, nil
+	}
+	return nil, fmt.Errorf("unexpected %q at offset %d", c, p.pos)
+}
+
+// loadPriceVolatility computes priceVolatility from the history file when
+// the configured scoring formula uses it
+func loadPriceVolatility(filename string, window time.Duration) error {
+	formula, ok := strategies["formula"].(ScoreFormula)
+	if !ok || !formula.Uses("volatility") || filename == "" {
+		return nil
+	}
+	observations, err := readHistory(filename, clock().Add(-window))
+	if err != nil {
+		return err
+	}
+
+	sums := map[string]float64{}
+	counts := map[string]int{}
+	for _, types := range priceSeries(observations) {
+		for instanceType, prices := range types {
+			if len(prices) < 2 {
+				continue
+			}
+			var mean, variance float64
+			for _, price := range prices {
+				mean += price
+			}
+			mean /= float64(len(prices))
+			if mean == 0 {
+				continue
+			}
+			for _, price := range prices {
+				variance += (price - mean) * (price - mean)
+			}
+			variance /= float64(len(prices))
+			sums[instanceType] += math.Sqrt(variance) / mean
+			counts[instanceType]++
+		}
+	}
+
+	priceVolatility = make(map[string]float64, len(sums))
+	for instanceType, sum := range sums {
+		priceVolatility[instanceType] = sum / float64(counts[instanceType])
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"time"
+)
+
+var (
+	serveFlags   = flag.NewFlagSet("serve", flag.ExitOnError)
+	serveAddr    = serveFlags.String("addr", ":8080", "address to listen on")
+	serveDir     = serveFlags.String("dir", "docs", "directory holding the published data; site files missing from it are served from the binary")
+	serveHistory = serveFlags.String("history", "docs/price_history.jsonl", "price history file backing the history API")
+	serveMaxAge  = serveFlags.Duration("ready-max-age", 90*time.Minute, "age of the served data after which /readyz reports not ready")
+	serveRefresh = serveFlags.Duration("refresh-interval", 0, "refresh the served data this often (e.g. 1h); 0 only refreshes through /admin/refresh")
+)
+
+// runServe implements the serve command: it serves the static site and
+// published data together with a JSON API over them
+func runServe(args []string) {
+	serveFlags.Parse(args)
+
+	if *serveEnvName != "" {
+		if err := applyEnvironment(*serveEnvName, serveFlags); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/", http.FileServer(newFrontendFS(*serveDir)))
+	mux.HandleFunc("/history/", handleHistoryFile)
+	mux.HandleFunc("/api/history/", handleHistory)
+	mux.HandleFunc("/api/query", handleQuery)
+	mux.HandleFunc("/api/match", handleMatch)
+	mux.HandleFunc("/admin/refresh", requireAdmin(handleAdminRefresh))
+	mux.HandleFunc("/healthz", handleHealthz)
+	mux.HandleFunc("/readyz", handleReadyz)
+	mux.HandleFunc("/version", handleVersion)
+
+	if err := os.MkdirAll(*serveDir, 0755); err != nil {
+		log.Fatalf("Error creating data directory: %v", err)
+	}
+	switch {
+	case *serveUpstream != "":
+		interval := *serveRefresh
+		if interval <= 0 {
+			interval = defaultUpstreamInterval
+		}
+		go func() {
+			if _, err := refreshFromUpstream(""); err != nil {
+				log.Printf("Error refreshing spot data: %v", err)
+			}
+			refreshPeriodically(interval)
+		}()
+	case *serveRefresh > 0 || len(config.Schedule.Windows) > 0:
+		go refreshPeriodically(*serveRefresh)
+	}
+	if peers := splitParam([]string{*servePeers}); len(peers) > 0 {
+		go syncPeersPeriodically(peers, *servePeerInterval)
+	}
+
+	server := &http.Server{
+		Addr:              *serveAddr,
+		Handler:           logRequests(mux),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	log.Printf("Serving %s on %s", *serveDir, *serveAddr)
+	log.Fatal(server.ListenAndServe())
+}
+
+// logRequests logs the method, path and duration of every request except
+// orchestration probes, which would drown out everything else
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		next.ServeHTTP(w, r)
+		if r.URL.Path == "/healthz" || r.URL.Path == "/readyz" {
+			return
+		}
+		log.Printf("%s %s (%s)", r.Method, r.URL.RequestURI(), time.Since(start).Round(time.Millisecond))
+	})
+}
+
+// writeJSON sends v as an indented JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		log.Printf("Error writing response: %v", err)
+	}
+}
+
+// allowRead answers methods other than GET and HEAD with an error and
+// reports whether the request may go on
+func allowRead(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return true
+	}
+	w.Header().Set("Allow", "GET, HEAD")
+	writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	return false
+}
+
+// writeError sends a JSON error response
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
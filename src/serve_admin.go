@@ -0,0 +1,174 @@
+package main
+
+import (
+	"crypto/subtle"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// refreshMu serializes refresh cycles of the served dataset
+var refreshMu sync.Mutex
+
+// errRefreshRunning is returned when a refresh is requested during another one
+var errRefreshRunning = errors.New("a refresh is already running")
+
+// requireAdmin wraps an admin handler with bearer token authentication
+// against SPOT_FINDER_ADMIN_TOKEN. Without the variable the endpoints are disabled.
+func requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := os.Getenv("SPOT_FINDER_ADMIN_TOKEN")
+		if token == "" {
+			writeError(w, http.StatusNotFound, "admin endpoints are disabled")
+			return
+		}
+		given := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, "invalid admin token")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// handleAdminRefresh serves POST /admin/refresh[?region=...], running a fetch
+// cycle immediately instead of waiting for the next scheduled one
+func handleAdminRefresh(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	region := r.URL.Query().Get("region")
+	start := time.Now()
+	data, err := servedRefresh()(region)
+	if errors.Is(err, errRefreshRunning) {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("Error refreshing spot data: %v", err)
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"region":       region,
+		"regions":      len(data.Regions),
+		"last_updated": data.LastUpdated,
+		"duration":     time.Since(start).Round(time.Millisecond).String(),
+	})
+}
+
+// refreshPeriodically runs full refresh cycles, every interval or as often
+// as the schedule's current window says
+func refreshPeriodically(interval time.Duration) {
+	wait, ok := config.Schedule.refreshInterval(time.Now(), interval)
+	for {
+		if !ok {
+			// Outside every run window; look again shortly
+			wait = time.Minute
+		}
+		time.Sleep(wait)
+
+		if wait, ok = config.Schedule.refreshInterval(time.Now(), interval); !ok {
+			continue
+		}
+		if _, err := servedRefresh()(""); err != nil {
+			log.Printf("Error refreshing spot data: %v", err)
+		}
+	}
+}
+
+// refreshServedData fetches fresh deals, for every region or only the given
+// one, and merges them into the served dataset
+func refreshServedData(region string) (SpotData, error) {
+	if !refreshMu.TryLock() {
+		return SpotData{}, errRefreshRunning
+	}
+	defer refreshMu.Unlock()
+
+	if err := loadPriceVolatility(*serveHistory, *historyWindow); err != nil {
+		return SpotData{}, err
+	}
+
+	filename := filepath.Join(*serveDir, "spot_data.json")
+	existing, err := readExistingData(filename)
+	if err != nil && !os.IsNotExist(err) {
+		return SpotData{}, err
+	}
+
+	var fresh SpotData
+	if region == "" {
+		if fresh, err = fetchSpotData(); err != nil {
+			return SpotData{}, err
+		}
+	} else {
+		deals, err := getSpotDeals(region)
+		if err != nil {
+			return SpotData{}, err
+		}
+		if len(deals) == 0 {
+			return SpotData{}, errors.New("no deals found for region " + region)
+		}
+		fresh = SpotData{
+			LastUpdated: clock().UTC().Format(time.RFC3339),
+			Regions:     map[string][]Instance{region: deals},
+			Sources:     map[string]string{region: sourceEC2Shop},
+			GlobalTop5:  replaceRegionDeals(existing.GlobalTop5, region, regionDeals(region, deals)),
+		}
+		fresh.RegionsUpdated = map[string]string{region: fresh.LastUpdated}
+	}
+
+	bucketSpotPrices(&fresh, config.PriceBucketWidth)
+
+	if *serveHistory != "" {
+		if _, err := recordHistory(*serveHistory, &fresh); err != nil {
+			return SpotData{}, err
+		}
+	}
+
+	merged := mergeSpotData(existing, fresh)
+	capRegions(&merged)
+	deriveSections(&merged)
+
+	if err := replaceServedData(filename, merged); err != nil {
+		return SpotData{}, err
+	}
+	log.Printf("Refreshed spot data for %d regions", len(fresh.Regions))
+
+	notifyPriceDrops(diffSpotData(existing, merged), clock(), filepath.Join(*serveDir, "alert_state.json"))
+	return merged, nil
+}
+
+// replaceServedData replaces the served dataset atomically, so concurrent
+// readers never see a partial write
+func replaceServedData(filename string, data SpotData) error {
+	temp := filename + ".tmp"
+	if err := writeDataFile(temp, data); err != nil {
+		return err
+	}
+	if err := os.Rename(temp, filename); err != nil {
+		return err
+	}
+	return writeMinifiedDataFile(filename, data)
+}
+
+// replaceRegionDeals updates a region's entries in the global top deals after
+// a refresh scoped to that region. Deals of regions outside the current top
+// are unknown here, so they only change on the next full refresh.
+func replaceRegionDeals(top []GlobalDeal, region string, deals []GlobalDeal) []GlobalDeal {
+	for _, existing := range top {
+		if existing.Region != region {
+			deals = append(deals, existing)
+		}
+	}
+	return topGlobalDeals(deals)
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRefreshServedDataEnrichesDeals(t *testing.T) {
+	mockUpstreams(t)
+	dir := t.TempDir()
+	setVar(t, serveDir, dir)
+	setVar(t, serveHistory, filepath.Join(dir, "price_history.jsonl"))
+	setFlag(t, "max-per-region", "2")
+
+	// The served dataset lists more instances than --max-per-region allows
+	content, err := os.ReadFile(filepath.Join("testdata", "spot_data.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "spot_data.json"), content, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := refreshServedData("eu-west-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	instances := data.Regions["eu-west-1"]
+	if len(instances) != 2 {
+		t.Fatalf("refreshed region has %d instances, want --max-per-region 2", len(instances))
+	}
+	recommended := 0
+	for _, instance := range instances {
+		if instance.RecommendedMaxPrice != "" {
+			recommended++
+		}
+	}
+	if recommended == 0 {
+		t.Errorf("no refreshed instance has a recommended max price: %+v", instances)
+	}
+}
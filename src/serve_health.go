@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"time"
+)
+
+// handleHealthz reports that the process is up and serving requests
+func handleHealthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// handleReadyz reports whether the served data is loaded and recent enough
+// to route traffic to this instance
+func handleReadyz(w http.ResponseWriter, r *http.Request) {
+	data, err := readExistingData(filepath.Join(*serveDir, "spot_data.json"))
+	if err != nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"status": "unavailable", "reason": "spot data is not loaded"})
+		return
+	}
+	updated, err := time.Parse(time.RFC3339, data.LastUpdated)
+	if err != nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"status": "unavailable", "reason": "spot data has no valid last_updated"})
+		return
+	}
+	age := time.Since(updated)
+	if age > *serveMaxAge {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{
+			"status":       "stale",
+			"reason":       fmt.Sprintf("spot data is %s old, over the %s limit", age.Round(time.Second), *serveMaxAge),
+			"last_updated": data.LastUpdated,
+		})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ready", "last_updated": data.LastUpdated})
+}
+
+// handleVersion reports the build metadata of the running binary
+func handleVersion(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, buildInfo())
+}
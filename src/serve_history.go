@@ -0,0 +1,228 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Pagination limits of the history API
+const (
+	defaultHistoryLimit = 1000
+	maxHistoryLimit     = 10000
+)
+
+// HistoryPoint is a price observation, or the aggregate of observations
+// falling in one step-sized window when downsampling
+type HistoryPoint struct {
+	Time    string  `json:"time"`
+	Region  string  `json:"region"`
+	Price   float64 `json:"price"` // mean over the window
+	Min     float64 `json:"min"`
+	Max     float64 `json:"max"`
+	Samples int     `json:"samples"`
+}
+
+// HistoryResponse is a page of an instance type's price history
+type HistoryResponse struct {
+	InstanceType string         `json:"instanceType"`
+	From         string         `json:"from"`
+	To           string         `json:"to"`
+	Step         string         `json:"step,omitempty"`
+	Offset       int            `json:"offset"`
+	Total        int            `json:"total"`
+	NextOffset   *int           `json:"nextOffset,omitempty"`
+	Points       []HistoryPoint `json:"points"`
+}
+
+// handleHistory serves GET /api/history/{type}?from=&to=&step=&region=&limit=&offset=
+// where from and to are RFC 3339 timestamps (default: the last 30 days) and
+// step is a Go duration such as 1h or 24h that downsamples the series
+func handleHistory(w http.ResponseWriter, r *http.Request) {
+	if !allowRead(w, r) {
+		return
+	}
+	instanceType := strings.TrimPrefix(r.URL.Path, "/api/history/")
+	if instanceType == "" || strings.Contains(instanceType, "/") {
+		writeError(w, http.StatusNotFound, "expected /api/history/{instance type}")
+		return
+	}
+
+	query := r.URL.Query()
+	to := clock().UTC()
+	from := to.AddDate(0, 0, -30)
+	var step time.Duration
+	limit, offset := defaultHistoryLimit, 0
+	var err error
+
+	if v := query.Get("from"); v != "" {
+		if from, err = time.Parse(time.RFC3339, v); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid from: "+err.Error())
+			return
+		}
+	}
+	if v := query.Get("to"); v != "" {
+		if to, err = time.Parse(time.RFC3339, v); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid to: "+err.Error())
+			return
+		}
+	}
+	if v := query.Get("step"); v != "" {
+		if step, err = time.ParseDuration(v); err != nil || step <= 0 {
+			writeError(w, http.StatusBadRequest, "invalid step: expected a positive duration such as 1h")
+			return
+		}
+	}
+	if v := query.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit <= 0 || limit > maxHistoryLimit {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid limit: expected 1 to %d", maxHistoryLimit))
+			return
+		}
+	}
+	if v := query.Get("offset"); v != "" {
+		if offset, err = strconv.Atoi(v); err != nil || offset < 0 {
+			writeError(w, http.StatusBadRequest, "invalid offset")
+			return
+		}
+	}
+	if !from.Before(to) {
+		writeError(w, http.StatusBadRequest, "from must be before to")
+		return
+	}
+
+	points, err := historyPoints(*serveHistory, instanceType, query.Get("region"), from, to, step)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response := HistoryResponse{
+		InstanceType: instanceType,
+		From:         from.Format(time.RFC3339),
+		To:           to.Format(time.RFC3339),
+		Offset:       offset,
+		Total:        len(points),
+		Points:       []HistoryPoint{},
+	}
+	if step > 0 {
+		response.Step = step.String()
+	}
+	if offset < len(points) {
+		end := offset + limit
+		if end < len(points) {
+			response.NextOffset = &end
+		} else {
+			end = len(points)
+		}
+		response.Points = points[offset:end]
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// historyPoints collects an instance type's observations in [from, to),
+// optionally for a single region, aggregated into step-sized windows when
+// step is positive, ordered by time then region
+func historyPoints(filename, instanceType, region string, from, to time.Time, step time.Duration) ([]HistoryPoint, error) {
+	files, err := historyArchives(filename, from)
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, filename)
+
+	type key struct {
+		window time.Time
+		region string
+	}
+	windows := make(map[key]*HistoryPoint)
+	var points []HistoryPoint
+
+	for _, name := range files {
+		err := scanHistoryFile(name, func(observation Observation, t time.Time) {
+			if observation.InstanceType != instanceType || t.Before(from) || !t.Before(to) {
+				return
+			}
+			if region != "" && observation.Region != region {
+				return
+			}
+			price, err := strconv.ParseFloat(observation.Price, 64)
+			if err != nil {
+				return
+			}
+
+			if step <= 0 {
+				points = append(points, HistoryPoint{t.UTC().Format(time.RFC3339), observation.Region, price, price, price, 1})
+				return
+			}
+
+			// Windows are aligned on from so every page uses the same grid
+			window := from.Add(t.Sub(from) / step * step)
+			k := key{window, observation.Region}
+			p, ok := windows[k]
+			if !ok {
+				p = &HistoryPoint{Time: window.UTC().Format(time.RFC3339), Region: observation.Region, Min: math.Inf(1), Max: math.Inf(-1)}
+				windows[k] = p
+			}
+			p.Price += price
+			p.Min = math.Min(p.Min, price)
+			p.Max = math.Max(p.Max, price)
+			p.Samples++
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, p := range windows {
+		p.Price /= float64(p.Samples)
+		points = append(points, *p)
+	}
+	sort.SliceStable(points, func(i, j int) bool {
+		if points[i].Time != points[j].Time {
+			return points[i].Time < points[j].Time
+		}
+		return points[i].Region < points[j].Region
+	})
+	return points, nil
+}
+
+// handleHistoryFile serves GET and HEAD /history/{name} for the raw price
+// history file and its monthly archives, wherever --history keeps them.
+// Range requests are honored, so clients can resume or tail large downloads.
+func handleHistoryFile(w http.ResponseWriter, r *http.Request) {
+	if !allowRead(w, r) {
+		return
+	}
+	name := strings.TrimPrefix(r.URL.Path, "/history/")
+	active := filepath.Base(*serveHistory)
+	isArchive := strings.HasPrefix(name, strings.TrimSuffix(active, ".jsonl")+"-") && strings.HasSuffix(name, ".jsonl.gz")
+	if strings.ContainsAny(name, `/\`) || (name != active && !isArchive) {
+		writeError(w, http.StatusNotFound, "expected /history/"+active+" or one of its monthly archives")
+		return
+	}
+
+	file, err := os.Open(filepath.Join(filepath.Dir(*serveHistory), name))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "history file not found")
+		return
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "reading history file failed")
+		return
+	}
+
+	// Appends and compaction change the size or modification time, which
+	// together tell versions apart for If-Range and caches
+	w.Header().Set("ETag", fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size()))
+	if !isArchive {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	}
+	http.ServeContent(w, r, name, info.ModTime(), file)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// defaultMatchLimit is the number of candidates /api/match returns by default
+const defaultMatchLimit = 20
+
+// handleMatch serves GET /api/match?vcpu=8&memory=32&arch=arm64&max_price=0.2,
+// returning the instances across regions that meet a workload's
+// requirements, cheapest first. Sizes are minimums, since any larger
+// instance also fits the workload.
+func handleMatch(w http.ResponseWriter, r *http.Request) {
+	if !allowRead(w, r) {
+		return
+	}
+
+	query, err := parseMatchQuery(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	data, err := readExistingData(filepath.Join(*serveDir, "spot_data.json"))
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, "spot data is not available")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, query.respond(data))
+}
+
+// parseMatchQuery turns the parameters of /api/match into a query
+func parseMatchQuery(params url.Values) (Query, error) {
+	query := Query{Sort: "price", Limit: defaultMatchLimit}
+	var err error
+
+	if v := params.Get("vcpu"); v != "" {
+		if query.MinVCPUS, err = strconv.Atoi(v); err != nil || query.MinVCPUS < 0 {
+			return Query{}, errors.New("invalid vcpu: expected a number of vCPUs")
+		}
+	}
+	if v := params.Get("memory"); v != "" {
+		if query.MinMemory, err = strconv.ParseFloat(v, 64); err != nil || query.MinMemory < 0 || math.IsNaN(query.MinMemory) || math.IsInf(query.MinMemory, 0) {
+			return Query{}, errors.New("invalid memory: expected GiB")
+		}
+	}
+	if v := params.Get("max_price"); v != "" {
+		if query.MaxPrice, err = strconv.ParseFloat(v, 64); err != nil || query.MaxPrice <= 0 || math.IsNaN(query.MaxPrice) || math.IsInf(query.MaxPrice, 0) {
+			return Query{}, errors.New("invalid max_price: expected a positive hourly price")
+		}
+	}
+	if v := params.Get("limit"); v != "" {
+		if query.Limit, err = strconv.Atoi(v); err != nil {
+			return Query{}, errors.New("invalid limit")
+		}
+	}
+	query.Architectures = splitParam(params["arch"])
+	query.Categories = splitParam(params["category"])
+	query.Regions = splitParam(params["region"])
+	for _, architecture := range query.Architectures {
+		if architecture != "arm64" && architecture != "x86_64" {
+			return Query{}, errors.New("arch must be arm64 or x86_64")
+		}
+	}
+	if err := query.validate(); err != nil {
+		return Query{}, err
+	}
+	return query, nil
+}
+
+// splitParam flattens repeated and comma-separated query parameter values
+func splitParam(values []string) []string {
+	var split []string
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				split = append(split, part)
+			}
+		}
+	}
+	return split
+}
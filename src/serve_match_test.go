@@ -0,0 +1,35 @@
+package main
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestParseMatchQuery(t *testing.T) {
+	tests := []struct {
+		raw     string
+		wantErr bool
+	}{
+		{raw: "vcpu=8&memory=32&arch=arm64&max_price=0.2"},
+		{raw: "region=eu-west-1,us-east-1&limit=5"},
+		{raw: "vcpu=-1", wantErr: true},
+		{raw: "memory=-4", wantErr: true},
+		{raw: "memory=NaN", wantErr: true},
+		{raw: "memory=Inf", wantErr: true},
+		{raw: "max_price=0", wantErr: true},
+		{raw: "max_price=NaN", wantErr: true},
+		{raw: "max_price=%2BInf", wantErr: true},
+		{raw: "max_price=-Inf", wantErr: true},
+		{raw: "arch=sparc", wantErr: true},
+		{raw: "limit=0", wantErr: true},
+	}
+	for _, test := range tests {
+		params, err := url.ParseQuery(test.raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := parseMatchQuery(params); (err != nil) != test.wantErr {
+			t.Errorf("parseMatchQuery(%q) error = %v, want error %v", test.raw, err, test.wantErr)
+		}
+	}
+}
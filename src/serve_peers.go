@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var (
+	servePeers        = serveFlags.String("peers", "", "comma-separated base URLs of other serve instances, or URLs of a published spot_data.json, to pull newer snapshots from")
+	servePeerInterval = serveFlags.Duration("peer-sync-interval", time.Minute, "how often to poll the peers for a newer snapshot")
+)
+
+// peerSnapshotURL returns where a peer publishes its dataset: a URL ending
+// in .json is used as is, anything else is a serve instance
+func peerSnapshotURL(peer string) string {
+	if strings.HasSuffix(peer, ".json") {
+		return peer
+	}
+	return strings.TrimSuffix(peer, "/") + "/spot_data.json"
+}
+
+// syncPeersPeriodically pulls the newest snapshot of the peers every
+// interval. Instances only ever adopt strictly newer data, so pulling
+// from each other converges without a leader or shared storage.
+func syncPeersPeriodically(peers []string, interval time.Duration) {
+	for {
+		syncFromPeers(peers)
+		time.Sleep(interval)
+	}
+}
+
+// syncFromPeers replaces the served dataset with the newest peer snapshot,
+// when one is newer. It skips the round while a refresh is running.
+func syncFromPeers(peers []string) {
+	filename := filepath.Join(*serveDir, "spot_data.json")
+	var newest SpotData
+	var newestPeer string
+	newestAt := servedDataUpdated(filename)
+
+	for _, peer := range peers {
+		data, err := fetchSnapshot(peerSnapshotURL(peer))
+		if err != nil {
+			log.Printf("Error syncing from peer %s: %v", peer, err)
+			continue
+		}
+		if updated, _ := time.Parse(time.RFC3339, data.LastUpdated); updated.After(newestAt) {
+			newest, newestAt, newestPeer = data, updated, peer
+		}
+	}
+	if newestPeer == "" {
+		return
+	}
+
+	if !refreshMu.TryLock() {
+		return
+	}
+	defer refreshMu.Unlock()
+	// A refresh may have finished while the peers were polled
+	if !newestAt.After(servedDataUpdated(filename)) {
+		return
+	}
+	if err := replaceServedData(filename, newest); err != nil {
+		log.Printf("Error adopting the snapshot of peer %s: %v", newestPeer, err)
+		return
+	}
+	log.Printf("Adopted the snapshot of peer %s, updated %s", newestPeer, newest.LastUpdated)
+}
+
+// servedDataUpdated returns when the served dataset was last updated, or
+// the zero time when there is none yet
+func servedDataUpdated(filename string) time.Time {
+	existing, err := readExistingData(filename)
+	if err != nil {
+		return time.Time{}
+	}
+	updated, _ := time.Parse(time.RFC3339, existing.LastUpdated)
+	return updated
+}
+
+// fetchSnapshot downloads and checks a published dataset, applying the
+// same limits as upstream responses
+func fetchSnapshot(url string) (SpotData, error) {
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return SpotData{}, fmt.Errorf("%w: %w", ErrUpstreamUnavailable, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return SpotData{}, fmt.Errorf("%w: unexpected status %s", ErrUpstreamUnavailable, resp.Status)
+	}
+
+	var data SpotData
+	if err := json.NewDecoder(newLimitedReader(resp.Body, maxResponseBytes)).Decode(&data); err != nil {
+		return SpotData{}, fmt.Errorf("%w: %w", ErrDecode, err)
+	}
+	if _, err := time.Parse(time.RFC3339, data.LastUpdated); err != nil || len(data.Regions) == 0 {
+		return SpotData{}, fmt.Errorf("%w: snapshot has no regions or no valid last_updated", ErrValidation)
+	}
+	for region, instances := range data.Regions {
+		if err := validateInstances(instances); err != nil {
+			return SpotData{}, fmt.Errorf("%w: region %s: %w", ErrValidation, region, err)
+		}
+	}
+	return data, nil
+}
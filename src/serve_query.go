@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Result limits of the query API
+const (
+	defaultQueryLimit = 100
+	maxQueryLimit     = 5000
+	maxQueryBodyBytes = 1 << 20
+)
+
+// Query is the filter accepted by POST /api/query. Zero values leave a
+// criterion unconstrained.
+type Query struct {
+	Regions       []string `json:"regions"`
+	Families      []string `json:"families"`      // e.g. "c6g", matching c6g.large, c6g.xlarge...
+	Categories    []string `json:"categories"`    // general, compute, memory, storage or gpu
+	Architectures []string `json:"architectures"` // arm64 or x86_64
+	MinVCPUS      int      `json:"minVcpus"`
+	MaxVCPUS      int      `json:"maxVcpus"`
+	MinMemory     float64  `json:"minMemoryGiB"`
+	MaxMemory     float64  `json:"maxMemoryGiB"`
+	MaxPrice      float64  `json:"maxPrice"` // hourly spot price
+	Sort          string   `json:"sort"`     // pricePerVCPU (default), price, savings, vcpus or memory
+	Limit         int      `json:"limit"`
+}
+
+// QueryResult is an instance matched by a query, with its region
+type QueryResult struct {
+	Region string `json:"region"`
+	Instance
+	PricePerVCPU float64 `json:"pricePerVCPU"`
+}
+
+// QueryResponse lists the best matches of a query
+type QueryResponse struct {
+	LastUpdated string        `json:"last_updated"`
+	Total       int           `json:"total"`
+	Results     []QueryResult `json:"results"`
+	License     string        `json:"license,omitempty"`
+	Attribution []Attribution `json:"attribution,omitempty"`
+}
+
+// queryOrders maps the sort names of a query to their orderings; prices sort
+// ascending, savings and sizes descending
+var queryOrders = map[string]func(a, b QueryResult) bool{
+	"pricePerVCPU": func(a, b QueryResult) bool { return a.PricePerVCPU < b.PricePerVCPU },
+	"price":        func(a, b QueryResult) bool { return parsePrice(a.SpotPrice) < parsePrice(b.SpotPrice) },
+	"savings": func(a, b QueryResult) bool {
+		return parseLeadingNumber(a.SpotSavingRate) > parseLeadingNumber(b.SpotSavingRate)
+	},
+	"vcpus":  func(a, b QueryResult) bool { return a.VCPUS > b.VCPUS },
+	"memory": func(a, b QueryResult) bool { return parseLeadingNumber(a.Memory) > parseLeadingNumber(b.Memory) },
+}
+
+// handleQuery serves POST /api/query, evaluating a Query against the
+// published dataset so dashboards can fetch exactly the rows they show
+func handleQuery(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	query, err := decodeQuery(http.MaxBytesReader(w, r.Body, maxQueryBodyBytes))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	data, err := readExistingData(filepath.Join(*serveDir, "spot_data.json"))
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, "spot data is not available")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, query.respond(data))
+}
+
+// decodeQuery reads and validates a Query in JSON, applying the defaults
+// for fields it leaves out
+func decodeQuery(r io.Reader) (Query, error) {
+	query := Query{Sort: "pricePerVCPU", Limit: defaultQueryLimit}
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&query); err != nil {
+		return query, fmt.Errorf("invalid query: %v", err)
+	}
+	return query, query.validate()
+}
+
+// respond evaluates the query against data, keeping up to Limit results
+func (q Query) respond(data SpotData) QueryResponse {
+	results := q.evaluate(data)
+	response := QueryResponse{data.LastUpdated, len(results), results, data.License, data.Attribution}
+	if len(results) > q.Limit {
+		response.Results = results[:q.Limit]
+	}
+	return response
+}
+
+// validate checks the query's sort order and bounds
+func (q *Query) validate() error {
+	if q.Sort == "" {
+		q.Sort = "pricePerVCPU"
+	}
+	if _, ok := queryOrders[q.Sort]; !ok {
+		return fmt.Errorf("unknown sort %q", q.Sort)
+	}
+	if q.Limit <= 0 || q.Limit > maxQueryLimit {
+		return fmt.Errorf("limit must be between 1 and %d", maxQueryLimit)
+	}
+	if q.MaxVCPUS > 0 && q.MinVCPUS > q.MaxVCPUS {
+		return fmt.Errorf("minVcpus is above maxVcpus")
+	}
+	if q.MaxMemory > 0 && q.MinMemory > q.MaxMemory {
+		return fmt.Errorf("minMemoryGiB is above maxMemoryGiB")
+	}
+	return nil
+}
+
+// evaluate returns every instance of data matching the query, in its sort order
+func (q Query) evaluate(data SpotData) []QueryResult {
+	regions := make(map[string]bool)
+	for _, region := range q.Regions {
+		regions[region] = true
+	}
+	families := make(map[string]bool)
+	for _, family := range q.Families {
+		families[strings.ToLower(family)] = true
+	}
+	categories := make(map[string]bool)
+	for _, category := range q.Categories {
+		categories[category] = true
+	}
+	architectures := make(map[string]bool)
+	for _, architecture := range q.Architectures {
+		architectures[architecture] = true
+	}
+
+	results := []QueryResult{}
+	for region, instances := range data.Regions {
+		if len(regions) > 0 && !regions[region] {
+			continue
+		}
+		for _, instance := range instances {
+			family, _, _ := strings.Cut(instance.InstanceType, ".")
+			if len(families) > 0 && !families[strings.ToLower(family)] {
+				continue
+			}
+			if len(categories) > 0 && !categories[classifyInstance("aws", instance.InstanceType)] {
+				continue
+			}
+			if len(architectures) > 0 && !architectures[instanceArchitecture(instance.InstanceType)] {
+				continue
+			}
+			if instance.VCPUS < q.MinVCPUS || (q.MaxVCPUS > 0 && instance.VCPUS > q.MaxVCPUS) {
+				continue
+			}
+			memory := parseLeadingNumber(instance.Memory)
+			if memory < q.MinMemory || (q.MaxMemory > 0 && memory > q.MaxMemory) {
+				continue
+			}
+			price, err := strconv.ParseFloat(instance.SpotPrice, 64)
+			if err != nil || instance.VCPUS == 0 || (q.MaxPrice > 0 && price > q.MaxPrice) {
+				continue
+			}
+			results = append(results, QueryResult{region, instance, price / float64(instance.VCPUS)})
+		}
+	}
+
+	// Break ties by region and type so paging through results is stable
+	less := queryOrders[q.Sort]
+	sort.Slice(results, func(i, j int) bool {
+		if less(results[i], results[j]) {
+			return true
+		}
+		if less(results[j], results[i]) {
+			return false
+		}
+		if results[i].Region != results[j].Region {
+			return results[i].Region < results[j].Region
+		}
+		return results[i].InstanceType < results[j].InstanceType
+	})
+	return results
+}
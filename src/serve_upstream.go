@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+var serveUpstream = serveFlags.String("upstream", "", "serve the spot_data.json published at this URL, refreshed every --refresh-interval (default 15m), instead of fetching the providers")
+
+// defaultUpstreamInterval is how often --upstream is polled without --refresh-interval
+const defaultUpstreamInterval = 15 * time.Minute
+
+// servedRefresh returns how the served dataset is refreshed: from the
+// providers, or from the published file with --upstream
+func servedRefresh() func(region string) (SpotData, error) {
+	if *serveUpstream != "" {
+		return refreshFromUpstream
+	}
+	return refreshServedData
+}
+
+// refreshFromUpstream replaces the served dataset with the one published at
+// --upstream whenever it changed. The published file is authoritative, so
+// an older one also replaces the served data, e.g. after a rollback.
+func refreshFromUpstream(region string) (SpotData, error) {
+	if region != "" {
+		return SpotData{}, errors.New("with --upstream, only the whole dataset can be refreshed")
+	}
+	data, err := fetchSnapshot(*serveUpstream)
+	if err != nil {
+		return SpotData{}, err
+	}
+
+	if !refreshMu.TryLock() {
+		return SpotData{}, errRefreshRunning
+	}
+	defer refreshMu.Unlock()
+
+	filename := filepath.Join(*serveDir, "spot_data.json")
+	existing, err := readExistingData(filename)
+	if err != nil && !os.IsNotExist(err) {
+		return SpotData{}, err
+	}
+	if data.LastUpdated == existing.LastUpdated {
+		return existing, nil
+	}
+
+	if *serveHistory != "" {
+		if err := appendHistory(*serveHistory, freshRegions(data)); err != nil {
+			return SpotData{}, err
+		}
+	}
+	if err := replaceServedData(filename, data); err != nil {
+		return SpotData{}, err
+	}
+	log.Printf("Refreshed spot data from %s, updated %s", *serveUpstream, data.LastUpdated)
+
+	notifyPriceDrops(diffSpotData(existing, data), clock(), filepath.Join(*serveDir, "alert_state.json"))
+	return data, nil
+}
+
+// freshRegions keeps the regions fetched by the run that last updated data,
+// so regions carried over from older runs are not recorded in the history
+// again under a newer time
+func freshRegions(data SpotData) SpotData {
+	fresh := SpotData{LastUpdated: data.LastUpdated, Regions: make(map[string][]Instance, len(data.Regions))}
+	for region, instances := range data.Regions {
+		if updated, ok := data.RegionsUpdated[region]; !ok || updated == data.LastUpdated {
+			fresh.Regions[region] = instances
+		}
+	}
+	return fresh
+}
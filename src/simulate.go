@@ -0,0 +1,104 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+var (
+	simulateFlags        = flag.NewFlagSet("simulate", flag.ExitOnError)
+	simulateInstanceType = simulateFlags.String("instance-type", "", "instance type to simulate (e.g. c6g.4xlarge)")
+	simulateRegion       = simulateFlags.String("region", "", "AWS region to simulate (e.g. eu-west-1)")
+	simulateHours        = simulateFlags.Float64("hours-per-day", 8, "hours the workload runs each day")
+	simulateDays         = simulateFlags.Int("days", 30, "number of trailing days to replay")
+	simulateHistory      = simulateFlags.String("history", "docs/price_history.jsonl", "price history file to replay")
+)
+
+// runSimulate implements the simulate command: it replays the recorded
+// price history of an instance and estimates what a daily workload would
+// have cost over the trailing period
+func runSimulate(args []string) {
+	simulateFlags.Parse(args)
+
+	if *simulateInstanceType == "" || *simulateRegion == "" || *simulateHours <= 0 || *simulateHours > 24 || *simulateDays <= 0 {
+		fmt.Fprintln(os.Stderr, "usage: simulate --instance-type <type> --region <region> [--hours-per-day 8] [--days 30]")
+		simulateFlags.PrintDefaults()
+		os.Exit(2)
+	}
+
+	hours := *simulateHours
+	end := clock().UTC()
+	start := end.AddDate(0, 0, -*simulateDays)
+
+	// Include older observations so the price in effect at the start is known
+	observations, err := readHistory(*simulateHistory, time.Time{})
+	if err != nil {
+		log.Fatalf("Error reading price history: %v", err)
+	}
+
+	type point struct {
+		t     time.Time
+		price float64
+	}
+	var points []point
+	for _, observation := range observations {
+		if observation.Region != *simulateRegion || observation.InstanceType != *simulateInstanceType {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, observation.Time)
+		price, perr := strconv.ParseFloat(observation.Price, 64)
+		if err != nil || perr != nil || t.After(end) {
+			continue
+		}
+		points = append(points, point{t, price})
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].t.Before(points[j].t) })
+
+	if len(points) == 0 {
+		log.Fatalf("No price history for %s in %s", *simulateInstanceType, *simulateRegion)
+	}
+
+	// Each simulated day pays the last price observed before it started
+	var dailyCosts []float64
+	next := 0
+	price := math.NaN()
+	for day := start; day.Before(end); day = day.AddDate(0, 0, 1) {
+		for next < len(points) && !points[next].t.After(day) {
+			price = points[next].price
+			next++
+		}
+		if !math.IsNaN(price) {
+			dailyCosts = append(dailyCosts, price*hours)
+		}
+	}
+
+	if len(dailyCosts) == 0 {
+		log.Fatalf("Price history for %s in %s does not cover the last %d days", *simulateInstanceType, *simulateRegion, *simulateDays)
+	}
+
+	total, low, high := 0.0, math.Inf(1), math.Inf(-1)
+	for _, cost := range dailyCosts {
+		total += cost
+		low = math.Min(low, cost)
+		high = math.Max(high, cost)
+	}
+	mean := total / float64(len(dailyCosts))
+	variance := 0.0
+	for _, cost := range dailyCosts {
+		variance += (cost - mean) * (cost - mean)
+	}
+	stddev := math.Sqrt(variance / float64(len(dailyCosts)))
+	latest := points[len(points)-1].price
+
+	fmt.Printf("Simulated %s in %s, %.1f hours/day over %d of the last %d days\n\n", *simulateInstanceType, *simulateRegion, *simulateHours, len(dailyCosts), *simulateDays)
+	fmt.Printf("Total cost:             $%.2f\n", total)
+	fmt.Printf("Average daily cost:     $%.4f (std dev $%.4f)\n", mean, stddev)
+	fmt.Printf("Cheapest / costliest:   $%.4f / $%.4f per day\n", low, high)
+	fmt.Printf("At today's price:       $%.2f for the same period\n", latest*hours*float64(len(dailyCosts)))
+}
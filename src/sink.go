@@ -0,0 +1,71 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+)
+
+// Snapshot is a version of the dataset handed to sinks for publishing
+type Snapshot struct {
+	Data SpotData
+	Diff SpotDiff
+	JSON []byte
+}
+
+// Sink publishes snapshots to a destination other than the repository
+type Sink interface {
+	Name() string
+	Publish(snapshot Snapshot) error
+}
+
+var cacheControl = flag.String("cache-control", "public, max-age=300", "Cache-Control header set on objects uploaded to storage sinks")
+
+// configuredSinks returns the sinks enabled on the command line, or an
+// error when one of them is misconfigured
+func configuredSinks() ([]Sink, error) {
+	var sinks []Sink
+	if *gcsBucket != "" {
+		sinks = append(sinks, &GCSSink{Bucket: *gcsBucket, Prefix: *gcsPrefix, CacheControl: *cacheControl})
+	}
+	if *azureContainerURL != "" {
+		sinks = append(sinks, &AzureBlobSink{ContainerURL: *azureContainerURL, Prefix: *azurePrefix, CacheControl: *cacheControl})
+	}
+	if *ipfsAPI != "" {
+		sinks = append(sinks, &IPFSSink{APIURL: *ipfsAPI, ManifestPath: *ipfsManifest})
+	}
+	if *mqttBroker != "" {
+		sink, err := newMQTTSink(*mqttBroker, *mqttTopicPrefix)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+	if *natsURL != "" {
+		sinks = append(sinks, &NATSSink{URL: *natsURL, Subject: *natsSubject})
+	}
+	if *kafkaRESTURL != "" {
+		sinks = append(sinks, &KafkaSink{RESTURL: *kafkaRESTURL, Topic: *kafkaTopic})
+	}
+	if *redisURL != "" {
+		sinks = append(sinks, &RedisSink{URL: *redisURL, KeyPrefix: *redisKeyPrefix, TTL: *redisTTL})
+	}
+	return sinks, nil
+}
+
+// publishSnapshot sends the snapshot to every sink, returning an error if any failed
+func publishSnapshot(sinks []Sink, snapshot Snapshot) error {
+	var failed []string
+	for _, sink := range sinks {
+		if err := sink.Publish(snapshot); err != nil {
+			log.Printf("Error publishing to %s: %v", sink.Name(), err)
+			failed = append(failed, sink.Name())
+			continue
+		}
+		log.Printf("Published spot data to %s", sink.Name())
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("sinks %v", failed)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+)
+
+var (
+	azureContainerURL = flag.String("azure-container-url", "", "Azure Blob Storage container URL to publish spot data to (SAS token read from AZURE_STORAGE_SAS_TOKEN)")
+	azurePrefix       = flag.String("azure-prefix", "", "blob name prefix within the Azure container")
+)
+
+// AzureBlobSink uploads snapshots to an Azure Blob Storage container
+type AzureBlobSink struct {
+	ContainerURL string
+	Prefix       string
+	CacheControl string
+}
+
+// Name identifies the sink in logs
+func (s *AzureBlobSink) Name() string {
+	return s.ContainerURL
+}
+
+// Publish uploads the JSON snapshot as a block blob with its cache-control header
+func (s *AzureBlobSink) Publish(snapshot Snapshot) error {
+	sas := strings.TrimPrefix(os.Getenv("AZURE_STORAGE_SAS_TOKEN"), "?")
+	if sas == "" {
+		return errors.New("AZURE_STORAGE_SAS_TOKEN must be set")
+	}
+
+	blobURL := strings.TrimSuffix(s.ContainerURL, "/") + "/" + path.Join(s.Prefix, "spot_data.json") + "?" + sas
+	req, err := http.NewRequest("PUT", blobURL, bytes.NewReader(snapshot.JSON))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("x-ms-version", "2021-08-06")
+	req.Header.Set("x-ms-blob-type", "BlockBlob")
+	req.Header.Set("x-ms-blob-content-type", "application/json")
+	req.Header.Set("x-ms-blob-cache-control", s.CacheControl)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		// Drop the request URL from transport errors so the SAS token is not logged
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return fmt.Errorf("upload failed: %w", urlErr.Err)
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("upload failed: %s: %s", resp.Status, msg)
+	}
+	return nil
+}
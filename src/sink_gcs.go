@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path"
+)
+
+const gcsMetadataTokenURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+
+var (
+	gcsBucket = flag.String("gcs-bucket", "", "Google Cloud Storage bucket to publish spot data to")
+	gcsPrefix = flag.String("gcs-prefix", "", "object name prefix within the GCS bucket")
+)
+
+// GCSSink uploads snapshots to a Google Cloud Storage bucket
+type GCSSink struct {
+	Bucket       string
+	Prefix       string
+	CacheControl string
+}
+
+// Name identifies the sink in logs
+func (s *GCSSink) Name() string {
+	return "gs://" + s.Bucket
+}
+
+// Publish uploads the JSON snapshot with its cache-control metadata
+func (s *GCSSink) Publish(snapshot Snapshot) error {
+	token, err := gcsAccessToken()
+	if err != nil {
+		return err
+	}
+
+	// A multipart upload sets the object metadata and content in one request
+	metadata, err := json.Marshal(map[string]string{
+		"name":         path.Join(s.Prefix, "spot_data.json"),
+		"contentType":  "application/json",
+		"cacheControl": s.CacheControl,
+	})
+	if err != nil {
+		return err
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for _, part := range []struct {
+		contentType string
+		content     []byte
+	}{
+		{"application/json; charset=UTF-8", metadata},
+		{"application/json", snapshot.JSON},
+	} {
+		w, err := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {part.contentType}})
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(part.content); err != nil {
+			return err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return err
+	}
+
+	uploadURL := fmt.Sprintf("https://storage.googleapis.com/upload/storage/v1/b/%s/o?uploadType=multipart", url.PathEscape(s.Bucket))
+	req, err := http.NewRequest("POST", uploadURL, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "multipart/related; boundary="+mw.Boundary())
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("upload failed: %s: %s", resp.Status, msg)
+	}
+	return nil
+}
+
+// gcsAccessToken returns an OAuth token from GCS_ACCESS_TOKEN or, on Google
+// Cloud, from the instance metadata server
+func gcsAccessToken() (string, error) {
+	if token := os.Getenv("GCS_ACCESS_TOKEN"); token != "" {
+		return token, nil
+	}
+
+	req, err := http.NewRequest("GET", gcsMetadataTokenURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("GCS_ACCESS_TOKEN is not set and the metadata server is unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("metadata server token request failed: %s: %s", resp.Status, msg)
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", err
+	}
+	if token.AccessToken == "" {
+		return "", errors.New("metadata server returned no access token")
+	}
+	return token.AccessToken, nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"strings"
+)
+
+var (
+	ipfsAPI      = flag.String("ipfs-api", "", "experimental: IPFS (Kubo) RPC API address to pin snapshots to, e.g. http://127.0.0.1:5001")
+	ipfsManifest = flag.String("ipfs-manifest", "docs/ipfs_manifest.json", "manifest recording the chain of pinned snapshot CIDs")
+)
+
+// IPFSManifestEntry records one pinned snapshot and links to its predecessor
+type IPFSManifestEntry struct {
+	CID         string `json:"cid"`
+	Previous    string `json:"previous,omitempty"`
+	LastUpdated string `json:"last_updated"`
+}
+
+// IPFSSink pins snapshots to an IPFS node and appends their CIDs to a manifest
+type IPFSSink struct {
+	APIURL       string
+	ManifestPath string
+}
+
+// Name identifies the sink in logs
+func (s *IPFSSink) Name() string {
+	return "ipfs " + s.APIURL
+}
+
+// Publish adds and pins the JSON snapshot, then chains its CID in the manifest
+func (s *IPFSSink) Publish(snapshot Snapshot) error {
+	cid, err := s.add(snapshot.JSON)
+	if err != nil {
+		return err
+	}
+
+	manifest, err := readIPFSManifest(s.ManifestPath)
+	if err != nil {
+		return err
+	}
+
+	entry := IPFSManifestEntry{CID: cid, LastUpdated: snapshot.Data.LastUpdated}
+	if len(manifest) > 0 {
+		if manifest[len(manifest)-1].CID == cid {
+			return nil
+		}
+		entry.Previous = manifest[len(manifest)-1].CID
+	}
+	manifest = append(manifest, entry)
+
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.ManifestPath, append(content, '\n'), 0644)
+}
+
+// add uploads content through the Kubo RPC API and returns its CID
+func (s *IPFSSink) add(content []byte) (string, error) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	w, err := mw.CreateFormFile("file", "spot_data.json")
+	if err != nil {
+		return "", err
+	}
+	if _, err := w.Write(content); err != nil {
+		return "", err
+	}
+	if err := mw.Close(); err != nil {
+		return "", err
+	}
+
+	addURL := strings.TrimSuffix(s.APIURL, "/") + "/api/v0/add?pin=true&cid-version=1"
+	resp, err := http.Post(addURL, mw.FormDataContentType(), &body)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("add failed: %s: %s", resp.Status, msg)
+	}
+
+	var added struct {
+		Hash string `json:"Hash"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&added); err != nil {
+		return "", err
+	}
+	if added.Hash == "" {
+		return "", errors.New("add returned no CID")
+	}
+	return added.Hash, nil
+}
+
+// readIPFSManifest loads the CID chain, treating a missing file as empty
+func readIPFSManifest(filename string) ([]IPFSManifestEntry, error) {
+	var manifest []IPFSManifestEntry
+	content, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return manifest, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(content, &manifest)
+	return manifest, err
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+var (
+	kafkaRESTURL = flag.String("kafka-rest-url", "", "Kafka REST Proxy to stream price change events to, e.g. http://localhost:8082")
+	kafkaTopic   = flag.String("kafka-topic", "spot-changes", "Kafka topic for price change events")
+)
+
+// KafkaSink streams price change events to a Kafka topic through the REST Proxy
+type KafkaSink struct {
+	RESTURL string
+	Topic   string
+}
+
+// Name identifies the sink in logs
+func (s *KafkaSink) Name() string {
+	return "kafka " + s.Topic
+}
+
+// Publish produces one record per new or repriced instance, keyed by
+// region and instance type so compacted topics keep the latest price
+func (s *KafkaSink) Publish(snapshot Snapshot) error {
+	updates := priceUpdates(snapshot)
+	if len(updates) == 0 {
+		return nil
+	}
+
+	type record struct {
+		Key   string      `json:"key"`
+		Value PriceUpdate `json:"value"`
+	}
+	var records []record
+	for _, update := range updates {
+		records = append(records, record{update.Region + "/" + update.InstanceType, update})
+	}
+	payload, err := json.Marshal(map[string]interface{}{"records": records})
+	if err != nil {
+		return err
+	}
+
+	produceURL := strings.TrimSuffix(s.RESTURL, "/") + "/topics/" + url.PathEscape(s.Topic)
+	resp, err := http.Post(produceURL, "application/vnd.kafka.json.v2+json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("produce failed: %s: %s", resp.Status, msg)
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"time"
+)
+
+var (
+	mqttBroker      = flag.String("mqtt-broker", "", "MQTT broker to publish price changes to, e.g. tcp://localhost:1883 or ssl://broker:8883")
+	mqttTopicPrefix = flag.String("mqtt-topic-prefix", "spot", "topic prefix for MQTT price updates (<prefix>/<region>/<instance type>)")
+)
+
+// PriceUpdate is the payload published for an instance whose price changed
+type PriceUpdate struct {
+	Region       string `json:"region"`
+	InstanceType string `json:"instanceType"`
+	OldPrice     string `json:"oldPrice,omitempty"`
+	NewPrice     string `json:"newPrice"`
+	Timestamp    string `json:"timestamp"`
+}
+
+// priceUpdates lists the new and repriced instances of a snapshot
+func priceUpdates(snapshot Snapshot) []PriceUpdate {
+	var updates []PriceUpdate
+	for _, added := range snapshot.Diff.Added {
+		updates = append(updates, PriceUpdate{added.Region, added.InstanceType, "", added.SpotPrice, snapshot.Data.LastUpdated})
+	}
+	for _, change := range snapshot.Diff.Changed {
+		updates = append(updates, PriceUpdate{change.Region, change.InstanceType, change.OldPrice, change.NewPrice, snapshot.Data.LastUpdated})
+	}
+	return updates
+}
+
+// MQTTSink publishes per-instance price changes as retained MQTT messages
+type MQTTSink struct {
+	Broker      string
+	TopicPrefix string
+	Username    string
+	Password    string
+}
+
+// newMQTTSink configures an MQTT sink with the credentials read from
+// MQTT_USERNAME and MQTT_PASSWORD. MQTT 3.1.1 (section 3.1.2.9) does not
+// allow a password without a username, which brokers would reject only
+// when the first update is published.
+func newMQTTSink(broker, topicPrefix string) (*MQTTSink, error) {
+	sink := &MQTTSink{
+		Broker:      broker,
+		TopicPrefix: topicPrefix,
+		Username:    os.Getenv("MQTT_USERNAME"),
+		Password:    os.Getenv("MQTT_PASSWORD"),
+	}
+	if sink.Password != "" && sink.Username == "" {
+		return nil, errors.New("MQTT_PASSWORD is set without MQTT_USERNAME")
+	}
+	return sink, nil
+}
+
+// Name identifies the sink in logs
+func (s *MQTTSink) Name() string {
+	return "mqtt " + s.Broker
+}
+
+// Publish sends one retained message per changed instance
+func (s *MQTTSink) Publish(snapshot Snapshot) error {
+	updates := priceUpdates(snapshot)
+	if len(updates) == 0 {
+		return nil
+	}
+
+	conn, err := dialMQTT(s.Broker, s.Username, s.Password)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	for _, update := range updates {
+		payload, err := json.Marshal(update)
+		if err != nil {
+			return err
+		}
+		topic := fmt.Sprintf("%s/%s/%s", s.TopicPrefix, update.Region, update.InstanceType)
+		if err := conn.publish(topic, payload); err != nil {
+			return err
+		}
+	}
+	return conn.disconnect()
+}
+
+// mqttConn is a minimal MQTT 3.1.1 client supporting QoS 0 publishing
+type mqttConn struct {
+	net.Conn
+	w *bufio.Writer
+}
+
+// dialMQTT connects to a tcp:// or ssl:// broker and completes the CONNECT handshake
+func dialMQTT(broker, username, password string) (*mqttConn, error) {
+	u, err := url.Parse(broker)
+	if err != nil {
+		return nil, err
+	}
+
+	var conn net.Conn
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	switch u.Scheme {
+	case "tcp", "mqtt":
+		conn, err = dialer.Dial("tcp", u.Host)
+	case "ssl", "tls", "mqtts":
+		conn, err = tls.DialWithDialer(dialer, "tcp", u.Host, &tls.Config{ServerName: u.Hostname()})
+	default:
+		return nil, fmt.Errorf("unsupported MQTT broker scheme %q", u.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(time.Minute))
+
+	// Variable header: protocol name, level 4 (3.1.1), flags and keep-alive
+	var flags byte = 0x02 // clean session
+	if username != "" {
+		flags |= 0x80
+	}
+	if password != "" {
+		flags |= 0x40
+	}
+	var packet bytes.Buffer
+	writeMQTTString(&packet, "MQTT")
+	packet.Write([]byte{4, flags, 0, 60})
+	writeMQTTString(&packet, fmt.Sprintf("spot-finder-%d", os.Getpid()))
+	if username != "" {
+		writeMQTTString(&packet, username)
+	}
+	if password != "" {
+		writeMQTTString(&packet, password)
+	}
+
+	c := &mqttConn{Conn: conn, w: bufio.NewWriter(conn)}
+	if err := c.writePacket(0x10, packet.Bytes()); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := c.w.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	connack := make([]byte, 4)
+	if _, err := io.ReadFull(conn, connack); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if connack[0] != 0x20 {
+		conn.Close()
+		return nil, errors.New("broker did not acknowledge the connection")
+	}
+	if connack[3] != 0 {
+		conn.Close()
+		return nil, fmt.Errorf("broker refused the connection (return code %d)", connack[3])
+	}
+	return c, nil
+}
+
+// publish sends a retained QoS 0 message
+func (c *mqttConn) publish(topic string, payload []byte) error {
+	var packet bytes.Buffer
+	writeMQTTString(&packet, topic)
+	packet.Write(payload)
+	return c.writePacket(0x31, packet.Bytes())
+}
+
+// disconnect flushes pending messages and ends the session cleanly
+func (c *mqttConn) disconnect() error {
+	if err := c.writePacket(0xE0, nil); err != nil {
+		return err
+	}
+	return c.w.Flush()
+}
+
+// writePacket writes a control packet with its variable-length remaining size
+func (c *mqttConn) writePacket(header byte, body []byte) error {
+	c.w.WriteByte(header)
+	n := len(body)
+	for {
+		b := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			b |= 0x80
+		}
+		c.w.WriteByte(b)
+		if n == 0 {
+			break
+		}
+	}
+	_, err := c.w.Write(body)
+	return err
+}
+
+// writeMQTTString writes a length-prefixed UTF-8 string
+func writeMQTTString(buf *bytes.Buffer, s string) {
+	buf.WriteByte(byte(len(s) >> 8))
+	buf.WriteByte(byte(len(s)))
+	buf.WriteString(s)
+}
@@ -0,0 +1,16 @@
+package main
+
+import "testing"
+
+func TestNewMQTTSinkRejectsPasswordWithoutUsername(t *testing.T) {
+	t.Setenv("MQTT_USERNAME", "")
+	t.Setenv("MQTT_PASSWORD", "secret")
+	if _, err := newMQTTSink("tcp://localhost:1883", "spot"); err == nil {
+		t.Error("newMQTTSink accepted a password without a username")
+	}
+
+	t.Setenv("MQTT_USERNAME", "spot-finder")
+	if _, err := newMQTTSink("tcp://localhost:1883", "spot"); err != nil {
+		t.Errorf("newMQTTSink: %v", err)
+	}
+}
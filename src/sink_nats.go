@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+var (
+	natsURL     = flag.String("nats-url", "", "NATS server to stream price change events to, e.g. nats://localhost:4222 (tls:// for TLS)")
+	natsSubject = flag.String("nats-subject", "spot.changes", "NATS subject for price change events")
+)
+
+// NATSSink streams price change events to a NATS subject
+type NATSSink struct {
+	URL     string
+	Subject string
+}
+
+// Name identifies the sink in logs
+func (s *NATSSink) Name() string {
+	return "nats " + s.URL
+}
+
+// Publish sends one event per new or repriced instance and waits for the
+// server to acknowledge them with a PONG
+func (s *NATSSink) Publish(snapshot Snapshot) error {
+	updates := priceUpdates(snapshot)
+	if len(updates) == 0 {
+		return nil
+	}
+
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		return err
+	}
+	conn, err := net.DialTimeout("tcp", u.Host, 10*time.Second)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(time.Minute))
+
+	// The server greets with INFO before any TLS upgrade
+	r := bufio.NewReader(conn)
+	info, err := r.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(info, "INFO ") {
+		return fmt.Errorf("unexpected greeting %q", strings.TrimSpace(info))
+	}
+	if u.Scheme == "tls" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: u.Hostname()})
+		if err := tlsConn.Handshake(); err != nil {
+			return err
+		}
+		conn = tlsConn
+		r = bufio.NewReader(conn)
+	}
+
+	options := map[string]interface{}{"verbose": false, "pedantic": false, "name": "ec2-spot-finder"}
+	if u.User != nil {
+		options["user"] = u.User.Username()
+		options["pass"], _ = u.User.Password()
+	}
+	if token := os.Getenv("NATS_TOKEN"); token != "" {
+		options["auth_token"] = token
+	}
+	connect, err := json.Marshal(options)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(conn)
+	fmt.Fprintf(w, "CONNECT %s\r\n", connect)
+	for _, update := range updates {
+		payload, err := json.Marshal(update)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "PUB %s %d\r\n%s\r\n", s.Subject, len(payload), payload)
+	}
+	w.WriteString("PING\r\n")
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	// Errors such as authorization failures arrive before the PONG
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		switch {
+		case strings.HasPrefix(line, "PONG"):
+			return nil
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("server error: %s", strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+	}
+}
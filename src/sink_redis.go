@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	redisURL       = flag.String("redis-url", "", "Redis server to cache current deals in, e.g. redis://:password@localhost:6379/0 (rediss:// for TLS)")
+	redisKeyPrefix = flag.String("redis-key-prefix", "spot", "key prefix for cached deals (<prefix>:<region>:<instance type>)")
+	redisTTL       = flag.Duration("redis-ttl", 48*time.Hour, "expiry of cached deals, so stale entries vanish if updates stop; 0 keeps them until replaced")
+)
+
+// RedisSink caches every current deal under <prefix>:<region>:<instance type>
+// and the cheapest per vCPU of each region under <prefix>:<region>:best
+type RedisSink struct {
+	URL       string
+	KeyPrefix string
+	TTL       time.Duration
+}
+
+// Name identifies the sink in logs
+func (s *RedisSink) Name() string {
+	return "redis " + s.KeyPrefix
+}
+
+// Publish pipelines a SET with expiry for every cached key
+func (s *RedisSink) Publish(snapshot Snapshot) error {
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		return err
+	}
+
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	var conn net.Conn
+	switch u.Scheme {
+	case "redis":
+		conn, err = dialer.Dial("tcp", u.Host)
+	case "rediss":
+		conn, err = tls.DialWithDialer(dialer, "tcp", u.Host, &tls.Config{ServerName: u.Hostname()})
+	default:
+		return fmt.Errorf("unsupported Redis URL scheme %q", u.Scheme)
+	}
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(time.Minute))
+
+	var commands [][]string
+	if password, ok := u.User.Password(); ok {
+		if name := u.User.Username(); name != "" {
+			commands = append(commands, []string{"AUTH", name, password})
+		} else {
+			commands = append(commands, []string{"AUTH", password})
+		}
+	}
+	if db := strings.TrimPrefix(u.Path, "/"); db != "" {
+		commands = append(commands, []string{"SELECT", db})
+	}
+
+	// Redis rejects EX 0, so a TTL under a second keeps the keys without expiry
+	var expiry []string
+	if seconds := int(s.TTL.Seconds()); seconds > 0 {
+		expiry = []string{"EX", strconv.Itoa(seconds)}
+	}
+	for region, instances := range snapshot.Data.Regions {
+		// Merged regional lists keep their historical order, so find the best deal explicitly
+		var best []byte
+		bestRatio := 0.0
+		for _, instance := range instances {
+			value, err := json.Marshal(instance)
+			if err != nil {
+				return err
+			}
+			commands = append(commands, append([]string{"SET", s.KeyPrefix + ":" + region + ":" + instance.InstanceType, string(value)}, expiry...))
+
+			price, err := strconv.ParseFloat(instance.SpotPrice, 64)
+			if err != nil || instance.VCPUS == 0 {
+				continue
+			}
+			if ratio := price / float64(instance.VCPUS); best == nil || ratio < bestRatio {
+				best, bestRatio = value, ratio
+			}
+		}
+		if best != nil {
+			commands = append(commands, append([]string{"SET", s.KeyPrefix + ":" + region + ":best", string(best)}, expiry...))
+		}
+	}
+
+	w := bufio.NewWriter(conn)
+	for _, command := range commands {
+		fmt.Fprintf(w, "*%d\r\n", len(command))
+		for _, arg := range command {
+			fmt.Fprintf(w, "$%d\r\n%s\r\n", len(arg), arg)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	// Every command answers with a simple string or an error line
+	r := bufio.NewReader(conn)
+	for range commands {
+		reply, err := r.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(reply, "-") {
+			return fmt.Errorf("redis error: %s", strings.TrimSpace(reply[1:]))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+)
+
+// fakeRedis accepts one connection, answers every command with +OK and,
+// once the client hangs up, sends the commands it received, each with its
+// arguments joined by spaces
+func fakeRedis(t *testing.T) (string, <-chan []string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	received := make(chan []string, 1)
+	go func() {
+		defer close(received)
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		var commands []string
+		r := bufio.NewReader(conn)
+		for {
+			var n int
+			if _, err := fmt.Fscanf(r, "*%d\r\n", &n); err != nil {
+				received <- commands
+				return
+			}
+			args := make([]string, n)
+			for i := range args {
+				var size int
+				fmt.Fscanf(r, "$%d\r\n", &size)
+				arg, _ := r.ReadString('\n')
+				args[i] = strings.TrimSuffix(arg, "\r\n")
+			}
+			commands = append(commands, strings.Join(args, " "))
+			conn.Write([]byte("+OK\r\n"))
+		}
+	}()
+	return "redis://" + listener.Addr().String(), received
+}
+
+func TestRedisSinkWithoutTTL(t *testing.T) {
+	url, received := fakeRedis(t)
+	sink := &RedisSink{URL: url, KeyPrefix: "spot"}
+	if err := sink.Publish(Snapshot{Data: readFixture(t)}); err != nil {
+		t.Fatal(err)
+	}
+
+	commands := <-received
+	if len(commands) == 0 {
+		t.Fatal("no command was sent")
+	}
+	for _, command := range commands {
+		if strings.Contains(command, " EX ") {
+			t.Errorf("command sets an expiry: %s", command)
+		}
+	}
+}
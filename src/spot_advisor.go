@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// spotAdvisorURL serves the data behind the AWS Spot Instance Advisor
+var spotAdvisorURL = "https://spot-bid-advisor.s3.amazonaws.com/spot-advisor-data.json"
+
+// spotAdvisorTTL is how long fetched interruption rates are reused
+const spotAdvisorTTL = time.Hour
+
+// interruptionLevels are the frequency-of-interruption ranges published by
+// the Spot Instance Advisor, least interrupted first. Instance.InterruptionRate
+// holds one of these labels.
+var interruptionLevels = []string{"<5%", "5-10%", "10-15%", "15-20%", ">20%"}
+
+// interruptionMidpoints estimate the interruption percentage of each level
+var interruptionMidpoints = []float64{2.5, 7.5, 12.5, 17.5, 25}
+
+// interruptionLevel returns the index of an interruption label, or
+// len(interruptionLevels) when the rate is unknown
+func interruptionLevel(label string) int {
+	for i, level := range interruptionLevels {
+		if level == label {
+			return i
+		}
+	}
+	return len(interruptionLevels)
+}
+
+// spotAdvisorData is the subset of the Spot Instance Advisor payload we use
+type spotAdvisorData struct {
+	SpotAdvisor map[string]map[string]map[string]struct {
+		Range int `json:"r"`
+	} `json:"spot_advisor"`
+}
+
+// spotAdvisorCache holds the interruption labels per region and instance
+// type, shared by concurrent region fetches
+var spotAdvisorCache struct {
+	sync.Mutex
+	fetched time.Time
+	rates   map[string]map[string]string
+}
+
+// interruptionRates returns the Linux interruption labels of a region's
+// instance types. Failures are logged and yield no rates, so ranking degrades
+// rather than the refresh failing; they are retried after spotAdvisorTTL.
+func interruptionRates(region string) map[string]string {
+	spotAdvisorCache.Lock()
+	defer spotAdvisorCache.Unlock()
+
+	if time.Since(spotAdvisorCache.fetched) > spotAdvisorTTL {
+		rates, err := fetchInterruptionRates()
+		if err != nil {
+			log.Printf("Error fetching spot interruption rates: %v", err)
+		}
+		spotAdvisorCache.rates = rates
+		spotAdvisorCache.fetched = time.Now()
+	}
+	return spotAdvisorCache.rates[region]
+}
+
+// fetchInterruptionRates downloads the Spot Instance Advisor data
+func fetchInterruptionRates() (map[string]map[string]string, error) {
+	resp, err := http.Get(spotAdvisorURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUpstreamUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	var data spotAdvisorData
+	if err := json.NewDecoder(newLimitedReader(resp.Body, maxResponseBytes)).Decode(&data); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDecode, err)
+	}
+
+	rates := make(map[string]map[string]string, len(data.SpotAdvisor))
+	for region, systems := range data.SpotAdvisor {
+		rates[region] = make(map[string]string)
+		for instanceType, advice := range systems["Linux"] {
+			if advice.Range >= 0 && advice.Range < len(interruptionLevels) {
+				rates[region][instanceType] = interruptionLevels[advice.Range]
+			}
+		}
+	}
+	return rates, nil
+}
+
+// applyInterruptionRates sets the interruption label of each instance
+func applyInterruptionRates(region string, instances []Instance) {
+	rates := interruptionRates(region)
+	for i := range instances {
+		instances[i].InterruptionRate = rates[instances[i].InstanceType]
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"time"
+)
+
+var (
+	statusFile    = flag.String("status-file", "docs/status.json", "write the data's age and staleness here on every run, even without changes; empty disables it")
+	heartbeatFile = flag.String("heartbeat-file", "docs/heartbeat.json", "record the time of every run here, even without changes; empty disables it")
+)
+
+// Heartbeat tells monitoring a run happened, so a run that found no
+// changes can be told apart from one that did not run at all
+type Heartbeat struct {
+	LastChecked string    `json:"last_checked"`
+	Changed     bool      `json:"changed"` // whether the run wrote new data
+	Stats       *RunStats `json:"stats,omitempty"`
+}
+
+// Status is a small file the site polls to warn visitors about old data
+type Status struct {
+	LastUpdated       string `json:"last_updated"`
+	GeneratedAt       string `json:"generated_at"`
+	DataAgeSeconds    int64  `json:"data_age_seconds"`
+	StaleAfterSeconds int64  `json:"stale_after_seconds"`
+	Stale             bool   `json:"stale"`
+}
+
+// recordRun writes the status and heartbeat files at the end of a refresh
+func recordRun(lastUpdated string, changed bool) error {
+	now := clock()
+	if *statusFile != "" {
+		if err := writeStatusFile(*statusFile, lastUpdated, now); err != nil {
+			return err
+		}
+	}
+	if *heartbeatFile != "" {
+		content, err := json.MarshalIndent(Heartbeat{now.UTC().Format(time.RFC3339), changed, runStats.Stats()}, "", "  ")
+		if err != nil {
+			return err
+		}
+		return writeJSONFile(*heartbeatFile, content)
+	}
+	return nil
+}
+
+// writeStatusFile records how old the published data is at now. The site
+// also compares generated_at to the threshold, so it notices when the
+// file itself stops being refreshed.
+func writeStatusFile(filename, lastUpdated string, now time.Time) error {
+	status := Status{
+		LastUpdated:       lastUpdated,
+		GeneratedAt:       now.UTC().Format(time.RFC3339),
+		StaleAfterSeconds: int64(time.Duration(config.StaleAfter).Seconds()),
+	}
+	if updated, err := time.Parse(time.RFC3339, lastUpdated); err == nil {
+		age := now.Sub(updated)
+		status.DataAgeSeconds = int64(age.Seconds())
+		status.Stale = age > time.Duration(config.StaleAfter)
+	} else {
+		status.Stale = true
+	}
+
+	content, err := json.MarshalIndent(status, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeJSONFile(filename, content)
+}
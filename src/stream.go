@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// expectDelim consumes the next token and checks it is the delimiter want
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != want {
+		return fmt.Errorf("expected %q, got %v", want, tok)
+	}
+	return nil
+}
+
+// shopInstance is an instance as listed by ec2.shop, whose Cost field is
+// the hourly on-demand price
+type shopInstance struct {
+	Instance
+	Cost upstreamPrice `json:"Cost"`
+}
+
+// upstreamPrice decodes a price given either as a JSON number or string,
+// leaving it empty when the value is missing, not numeric or not finite
+type upstreamPrice string
+
+func (p *upstreamPrice) UnmarshalJSON(data []byte) error {
+	value := strings.Trim(string(data), `"`)
+	if price, err := strconv.ParseFloat(value, 64); err != nil || math.IsNaN(price) || math.IsInf(price, 0) {
+		*p = ""
+		return nil
+	}
+	*p = upstreamPrice(value)
+	return nil
+}
+
+// decodePrices streams an ec2.shop response, validating every instance and
+// keeping only those accepted by keep, so rejected entries are never held in memory
+func decodePrices(r io.Reader, keep func(Instance) bool) ([]Instance, error) {
+	dec := json.NewDecoder(r)
+	if err := expectDelim(dec, '{'); err != nil {
+		return nil, err
+	}
+
+	var kept []Instance
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		// Match field names case-insensitively like json.Unmarshal does
+		if key, _ := tok.(string); !strings.EqualFold(key, "Prices") {
+			var skipped json.RawMessage
+			if err := dec.Decode(&skipped); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if err := expectDelim(dec, '['); err != nil {
+			return nil, err
+		}
+		for i := 0; dec.More(); i++ {
+			if i == maxInstancesPerRegion {
+				return nil, fmt.Errorf("%w: more than %d instances per region", ErrValidation, maxInstancesPerRegion)
+			}
+			var upstream shopInstance
+			if err := dec.Decode(&upstream); err != nil {
+				return nil, err
+			}
+			instance := upstream.Instance
+			instance.OnDemandPrice = string(upstream.Cost)
+			instance = recomputeSavingRate(instance)
+			if err := validateInstance(i, instance); err != nil {
+				return nil, fmt.Errorf("%w: %w", ErrValidation, err)
+			}
+			if keep(instance) {
+				kept = append(kept, instance)
+			}
+		}
+		if err := expectDelim(dec, ']'); err != nil {
+			return nil, err
+		}
+	}
+
+	return kept, expectDelim(dec, '}')
+}
+
+// decodeRegions streams the AWS locations list, keeping regions accepted by keep
+func decodeRegions(r io.Reader, keep func(Region) bool) ([]Region, error) {
+	dec := json.NewDecoder(r)
+	if err := expectDelim(dec, '{'); err != nil {
+		return nil, err
+	}
+
+	var kept []Region
+	for dec.More() {
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		var region Region
+		if err := dec.Decode(&region); err != nil {
+			return nil, err
+		}
+		if keep(region) {
+			kept = append(kept, region)
+		}
+	}
+
+	return kept, expectDelim(dec, '}')
+}
+
+// writeSpotData streams the dataset as indented JSON, encoding one region at a
+// time. The output is byte-for-byte what json.Encoder with a two-space indent
+// produces, keeping Git diffs of the published file stable.
+func writeSpotData(w io.Writer, data SpotData) error {
+	return streamSpotData(w, data, "  ")
+}
+
+// writeMinifiedSpotData streams the dataset as compact JSON, for the site to fetch
+func writeMinifiedSpotData(w io.Writer, data SpotData) error {
+	return streamSpotData(w, data, "")
+}
+
+// streamSpotData streams the dataset indented by indent, or compact when
+// it is empty, matching json.Encoder with that indent
+func streamSpotData(w io.Writer, data SpotData, indent string) error {
+	// Encode everything except the regions, then splice them in where the
+	// empty placeholder object sits
+	regions := data.Regions
+	if len(regions) > 0 {
+		data.Regions = map[string][]Instance{}
+	}
+
+	var skeleton bytes.Buffer
+	encoder := json.NewEncoder(&skeleton)
+	encoder.SetIndent("", indent)
+	if err := encoder.Encode(data); err != nil {
+		return err
+	}
+
+	newline, space := "", ""
+	if indent != "" {
+		newline, space = "\n"+indent, " "
+	}
+	placeholder := newline + `"regions":` + space + "{}"
+	at := bytes.Index(skeleton.Bytes(), []byte(placeholder))
+	if at < 0 || len(regions) == 0 {
+		_, err := w.Write(skeleton.Bytes())
+		return err
+	}
+	head, tail := skeleton.Bytes()[:at+len(placeholder)-1], skeleton.Bytes()[at+len(placeholder):]
+
+	if _, err := w.Write(head); err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(regions))
+	for name := range regions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for i, name := range names {
+		key, err := json.Marshal(name)
+		if err != nil {
+			return err
+		}
+		var instances []byte
+		if indent != "" {
+			instances, err = json.MarshalIndent(regions[name], newline[1:]+indent, indent)
+		} else {
+			instances, err = json.Marshal(regions[name])
+		}
+		if err != nil {
+			return err
+		}
+		sep := ","
+		if i == 0 {
+			sep = ""
+		}
+		if _, err := fmt.Fprintf(w, "%s%s%s%s:%s%s", sep, newline, indent, key, space, instances); err != nil {
+			return err
+		}
+	}
+
+	if _, err := io.WriteString(w, newline+"}"); err != nil {
+		return err
+	}
+	_, err := w.Write(tail)
+	return err
+}
+
+// writeDataFile streams the dataset to filename
+func writeDataFile(filename string, data SpotData) error {
+	return createDataFile(filename, data, writeSpotData)
+}
+
+// createDataFile streams the dataset to filename with encode
+func createDataFile(filename string, data SpotData, encode func(io.Writer, SpotData) error) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+	if err := encode(w, data); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+)
+
+// printInstanceTable writes instances as an aligned table including their
+// price per vCPU; note, when set, fills a trailing column for each row
+func printInstanceTable(out io.Writer, instances []Instance, note func(Instance) string) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprint(w, "INSTANCE TYPE\tVCPUS\tMEMORY\tSPOT PRICE\tPRICE PER VCPU")
+	if note != nil {
+		fmt.Fprint(w, "\tCHANGE")
+	}
+	fmt.Fprintln(w)
+
+	for _, instance := range instances {
+		price, _ := strconv.ParseFloat(instance.SpotPrice, 64)
+		fmt.Fprintf(w, "%s\t%d\t%s\t$%s\t$%.6f", instance.InstanceType, instance.VCPUS, instance.Memory, instance.SpotPrice, price/float64(instance.VCPUS))
+		if note != nil {
+			fmt.Fprintf(w, "\t%s", note(instance))
+		}
+		fmt.Fprintln(w)
+	}
+	w.Flush()
+}
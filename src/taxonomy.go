@@ -0,0 +1,78 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Workload categories shared by every provider's instance types
+const (
+	categoryGeneral = "general"
+	categoryCompute = "compute"
+	categoryMemory  = "memory"
+	categoryStorage = "storage"
+	categoryGPU     = "gpu" // GPUs and other accelerators
+)
+
+// awsFamilyCategories maps the letter prefix of an EC2 family (c6gn → c,
+// inf2 → inf) to its category
+var awsFamilyCategories = map[string]string{
+	"a": categoryGeneral, "m": categoryGeneral, "t": categoryGeneral, "mac": categoryGeneral,
+	"c": categoryCompute, "hpc": categoryCompute,
+	"r": categoryMemory, "x": categoryMemory, "z": categoryMemory, "u": categoryMemory,
+	"d": categoryStorage, "h": categoryStorage, "i": categoryStorage, "im": categoryStorage, "is": categoryStorage,
+	"p": categoryGPU, "g": categoryGPU, "dl": categoryGPU, "inf": categoryGPU, "trn": categoryGPU, "f": categoryGPU, "vt": categoryGPU,
+}
+
+// hetznerLineCategories maps the letter prefix of a Hetzner server type
+// (cpx31 → cpx) to its category: shared vCPU lines are general purpose,
+// dedicated vCPU ones compute
+var hetznerLineCategories = map[string]string{
+	"cx": categoryGeneral, "cpx": categoryGeneral, "cax": categoryGeneral,
+	"ccx": categoryCompute,
+}
+
+// digitalOceanPlanCategories maps the letter prefix of a Droplet plan
+// (c2-4vcpu-8gb → c, so1_5-2vcpu-16gb → so) to its category
+var digitalOceanPlanCategories = map[string]string{
+	"s": categoryGeneral, "g": categoryGeneral, "gd": categoryGeneral,
+	"c":   categoryCompute,
+	"m":   categoryMemory,
+	"so":  categoryStorage,
+	"gpu": categoryGPU,
+}
+
+// classifyInstance returns the workload category of a provider's instance
+// type, or an empty string when it is not recognized
+func classifyInstance(provider, instanceType string) string {
+	instanceType = strings.ToLower(instanceType)
+	switch provider {
+	case "aws":
+		family, _, _ := strings.Cut(instanceType, ".")
+		family, _, _ = strings.Cut(family, "-") // u-6tb1
+		return awsFamilyCategories[family[:letterPrefix(family)]]
+	case "hetzner":
+		return hetznerLineCategories[instanceType[:letterPrefix(instanceType)]]
+	case "digitalocean":
+		plan, _, _ := strings.Cut(instanceType, "-")
+		return digitalOceanPlanCategories[plan[:letterPrefix(plan)]]
+	}
+	return ""
+}
+
+// letterPrefix returns the length of the leading letters of s
+func letterPrefix(s string) int {
+	for i, r := range s {
+		if !unicode.IsLetter(r) {
+			return i
+		}
+	}
+	return len(s)
+}
+
+// applyCategories sets the workload category of each of a provider's instances
+func applyCategories(provider string, instances []Instance) {
+	for i := range instances {
+		instances[i].Category = classifyInstance(provider, instances[i].InstanceType)
+	}
+}
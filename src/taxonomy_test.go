@@ -0,0 +1,27 @@
+package main
+
+import "testing"
+
+func TestClassifyInstance(t *testing.T) {
+	tests := []struct {
+		provider, instanceType, want string
+	}{
+		{"aws", "c6gn.4xlarge", categoryCompute},
+		{"aws", "u-6tb1.metal", categoryMemory},
+		{"aws", "inf2.xlarge", categoryGPU},
+		{"hetzner", "cpx31", categoryGeneral},
+		{"hetzner", "cax21", categoryGeneral},
+		{"hetzner", "ccx33", categoryCompute},
+		{"digitalocean", "s-2vcpu-4gb-amd", categoryGeneral},
+		{"digitalocean", "c2-4vcpu-8gb", categoryCompute},
+		{"digitalocean", "m3-8vcpu-64gb", categoryMemory},
+		{"digitalocean", "so1_5-2vcpu-16gb", categoryStorage},
+		{"digitalocean", "gpu-h100x1-80gb", categoryGPU},
+		{"hetzner", "unknown", ""},
+	}
+	for _, test := range tests {
+		if got := classifyInstance(test.provider, test.instanceType); got != test.want {
+			t.Errorf("classifyInstance(%q, %q) = %q, want %q", test.provider, test.instanceType, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// templateFiles are the --template files rendered on every refresh
+var templateFiles stringList
+
+func init() {
+	flag.Var(&templateFiles, "template", "render a text/template file with the dataset to the same path without its .tmpl extension (repeatable)")
+}
+
+// stringList is a flag value collecting every occurrence of a repeatable flag
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// templateFuncs are the helpers available to --template files
+var templateFuncs = template.FuncMap{
+	"pricePerVCPU": pricePerVCPU,
+	"pricePerGB":   pricePerGB,
+	"float":        parsePrice,
+	"regions": func(data SpotData) []string {
+		names := make([]string, 0, len(data.Regions))
+		for name := range data.Regions {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return names
+	},
+	"rank": func(strategy string, instances []Instance) ([]Instance, error) {
+		s, err := strategyNamed(strategy)
+		if err != nil {
+			return nil, err
+		}
+		ranked := append([]Instance(nil), instances...)
+		rankInstances(ranked, s)
+		return ranked, nil
+	},
+	"first": func(n int, instances []Instance) []Instance {
+		if len(instances) > n {
+			return instances[:n]
+		}
+		return instances
+	},
+	"json": func(v interface{}) (string, error) {
+		content, err := json.Marshal(v)
+		return string(content), err
+	},
+	"money": func(price float64) string {
+		return "$" + strconv.FormatFloat(price, 'f', 4, 64)
+	},
+	"join":  strings.Join,
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+}
+
+// renderTemplates writes every --template file rendered with data
+func renderTemplates(files []string, data SpotData) error {
+	for _, name := range files {
+		output := strings.TrimSuffix(name, ".tmpl")
+		if output == name {
+			return fmt.Errorf("template %s: expected a .tmpl extension", name)
+		}
+
+		tmpl, err := template.New(filepath.Base(name)).Funcs(templateFuncs).Option("missingkey=error").ParseFiles(name)
+		if err != nil {
+			return err
+		}
+		file, err := os.Create(output)
+		if err != nil {
+			return err
+		}
+		if err := tmpl.Execute(file, data); err != nil {
+			file.Close()
+			return fmt.Errorf("template %s: %w", name, err)
+		}
+		if err := file.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,9 @@
+// Generated by ec2-spot-finder from spot_data.json. Do not edit.
+
+x86_64_region        = "ap-south-1"
+x86_64_instance_type = "c6a.4xlarge"
+x86_64_spot_price    = "0.1366"
+
+arm64_region        = "us-east-1"
+arm64_instance_type = "t4g.2xlarge"
+arm64_spot_price    = "0.1197"
@@ -0,0 +1,74 @@
+// Generated by ec2-spot-finder from spot_data.json. Do not edit.
+{
+  "attribution": [
+    {
+      "source": "Amazon Web Services",
+      "url": "https://aws.amazon.com/ec2/spot/instance-advisor/",
+      "notice": "Interruption frequencies from the Amazon EC2 Spot Instance Advisor."
+    },
+    {
+      "source": "ec2.shop",
+      "url": "https://ec2.shop",
+      "notice": "Spot and on-demand prices provided by ec2.shop."
+    }
+  ],
+  "best": {
+    "ap-south-1": {
+      "instanceType": "c6g.8xlarge",
+      "vcpus": 32,
+      "memory": "64 GiB",
+      "spotPrice": 0.2352,
+      "pricePerVCPU": 0.00735,
+      "interruptionRate": "\u003c5%"
+    },
+    "eu-west-1": {
+      "instanceType": "a1.metal",
+      "vcpus": 16,
+      "memory": "32 GiB",
+      "spotPrice": 0.173,
+      "pricePerVCPU": 0.0108125,
+      "interruptionRate": "\u003c5%"
+    },
+    "us-east-1": {
+      "instanceType": "a1.metal",
+      "vcpus": 16,
+      "memory": "32 GiB",
+      "spotPrice": 0.1723,
+      "pricePerVCPU": 0.01076875,
+      "interruptionRate": "\u003c5%"
+    }
+  },
+  "globalTop5": [
+    {
+      "instanceType": "c6g.8xlarge",
+      "cpus": 32,
+      "memory": "64 GiB",
+      "price": 0.2352,
+      "pricePerVCPU": 0.00735,
+      "region": "ap-south-1",
+      "onDemandPrice": 0.784,
+      "interruptionRate": "\u003c5%"
+    },
+    {
+      "instanceType": "a1.metal",
+      "cpus": 16,
+      "memory": "32 GiB",
+      "price": 0.1723,
+      "pricePerVCPU": 0.01076875,
+      "region": "us-east-1",
+      "onDemandPrice": 0.5221,
+      "interruptionRate": "\u003c5%"
+    },
+    {
+      "instanceType": "a1.metal",
+      "cpus": 16,
+      "memory": "32 GiB",
+      "price": 0.173,
+      "pricePerVCPU": 0.0108125,
+      "region": "eu-west-1",
+      "onDemandPrice": 0.3761,
+      "interruptionRate": "\u003c5%"
+    }
+  ],
+  "lastUpdated": "2024-01-01T00:00:00Z"
+}
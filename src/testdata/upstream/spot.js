@@ -0,0 +1 @@
+callback({"vers":0.01,"config":{"rate":"perhr","valueColumns":["linux","mswin"],"currencies":["USD"],"regions":[{"region":"us-east","footnotes":{"*":"notAvailableForCCorCGPU"},"instanceTypes":[{"type":"generalCurrentGen","sizes":[{"size":"c6g.4xlarge","valueColumns":[{"name":"linux","prices":{"USD":"0.1638"}},{"name":"mswin","prices":{"USD":"N/A*"}}]},{"size":"m6g.4xlarge","valueColumns":[{"name":"linux","prices":{"USD":"0.2035"}},{"name":"mswin","prices":{"USD":"N/A*"}}]},{"size":"c7g.16xlarge","valueColumns":[{"name":"linux","prices":{"USD":"N/A*"}},{"name":"mswin","prices":{"USD":"N/A*"}}]}]}]},{"region":"eu-ireland","instanceTypes":[{"type":"generalCurrentGen","sizes":[{"size":"c6g.4xlarge","valueColumns":[{"name":"linux","prices":{"USD":"0.1970"}}]}]}]}]}});
@@ -0,0 +1,40 @@
+package main
+
+import "strings"
+
+// regionDeals returns the candidates of a region for the global top deals.
+// With the default limits that is its best deal; otherwise every instance
+// competes, so a region whose best family is capped can still place another.
+func regionDeals(region string, instances []Instance) []GlobalDeal {
+	if config.TopMaxPerRegion == 1 && config.TopMaxPerFamily == 0 {
+		return []GlobalDeal{bestDeal(region, instances)}
+	}
+
+	deals := make([]GlobalDeal, len(instances))
+	for i, instance := range instances {
+		deals[i] = newGlobalDeal(region, instance)
+	}
+	return deals
+}
+
+// diverseTop keeps the first n of the ranked deals, skipping those that
+// would give a family more than perFamily entries or a region more than
+// perRegion; a limit of 0 or less does not apply
+func diverseTop(ranked []GlobalDeal, n, perFamily, perRegion int) []GlobalDeal {
+	families := map[string]int{}
+	regions := map[string]int{}
+	var top []GlobalDeal
+	for _, deal := range ranked {
+		if len(top) == n {
+			break
+		}
+		family, _, _ := strings.Cut(deal.InstanceType, ".")
+		if (perFamily > 0 && families[family] >= perFamily) || (perRegion > 0 && regions[deal.Region] >= perRegion) {
+			continue
+		}
+		families[family]++
+		regions[deal.Region]++
+		top = append(top, deal)
+	}
+	return top
+}
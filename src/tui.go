@@ -0,0 +1,383 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+var (
+	tuiFlags  = flag.NewFlagSet("tui", flag.ExitOnError)
+	tuiData   = tuiFlags.String("data", "docs/spot_data.json", "published dataset to explore, a file or an http(s) URL")
+	tuiRegion = tuiFlags.String("region", "", "region shown first; empty shows every region")
+)
+
+// Further ANSI escape sequences used to render the tui screen
+const (
+	ansiAltScreen  = "\033[?1049h\033[?25l" // switch to the alternate screen, hide the cursor
+	ansiMainScreen = "\033[?25h\033[?1049l"
+	ansiReverse    = "\033[7m"
+)
+
+const tuiHelp = "j/k move  s sort  r/R region  / filter  q quit"
+
+// tuiRow is an instance of a region in the tui table
+type tuiRow struct {
+	Region string
+	Instance
+}
+
+// tuiModel is the state of the tui: the rows of the dataset and how they
+// are filtered, sorted and scrolled. Keys update it, and it renders the
+// screen; the terminal handling is kept out of it.
+type tuiModel struct {
+	rows       []tuiRow
+	regions    []string
+	strategies []string
+
+	region   int // index in regions, -1 for every region
+	strategy int // index in strategies
+	filter   string
+	editing  bool // typing the filter
+	cursor   int
+	offset   int // first visible row
+
+	visible []tuiRow
+}
+
+// newTUIModel lists the instances of data, starting on region when set
+func newTUIModel(data SpotData, region string) *tuiModel {
+	m := &tuiModel{region: -1}
+	for name, instances := range data.Regions {
+		m.regions = append(m.regions, name)
+		for _, instance := range instances {
+			m.rows = append(m.rows, tuiRow{name, instance})
+		}
+	}
+	sort.Strings(m.regions)
+	for i, name := range m.regions {
+		if name == region {
+			m.region = i
+		}
+	}
+	for name := range strategies {
+		m.strategies = append(m.strategies, name)
+	}
+	sort.Strings(m.strategies)
+	for i, name := range m.strategies {
+		if name == "cheapest_per_vcpu" {
+			m.strategy = i
+		}
+	}
+	m.update()
+	return m
+}
+
+// update recomputes the visible rows after the region, sort or filter changed
+func (m *tuiModel) update() {
+	m.visible = m.visible[:0]
+	filter := strings.ToLower(m.filter)
+	for _, row := range m.rows {
+		if m.region >= 0 && row.Region != m.regions[m.region] {
+			continue
+		}
+		if filter != "" && !strings.Contains(row.InstanceType, filter) {
+			continue
+		}
+		m.visible = append(m.visible, row)
+	}
+	strategy := strategies[m.strategies[m.strategy]]
+	sort.SliceStable(m.visible, func(i, j int) bool {
+		if strategy.Less(m.visible[i].Instance, m.visible[j].Instance) {
+			return true
+		}
+		if strategy.Less(m.visible[j].Instance, m.visible[i].Instance) {
+			return false
+		}
+		return m.visible[i].Region+m.visible[i].InstanceType < m.visible[j].Region+m.visible[j].InstanceType
+	})
+	m.move(0)
+}
+
+// move moves the cursor by delta rows, staying within the visible rows
+func (m *tuiModel) move(delta int) {
+	m.cursor += delta
+	if m.cursor >= len(m.visible) {
+		m.cursor = len(m.visible) - 1
+	}
+	if m.cursor < 0 {
+		m.cursor = 0
+	}
+}
+
+// handleKey applies a key read by readKeys and reports whether to quit
+func (m *tuiModel) handleKey(key string, pageSize int) bool {
+	if m.editing {
+		switch key {
+		case "enter":
+			m.editing = false
+		case "esc":
+			m.editing = false
+			m.filter = ""
+		case "backspace":
+			if m.filter != "" {
+				m.filter = m.filter[:len(m.filter)-1]
+			}
+		case "ctrl-c":
+			return true
+		default:
+			if len(key) == 1 {
+				m.filter += key
+			}
+		}
+		m.update()
+		return false
+	}
+
+	switch key {
+	case "q", "ctrl-c":
+		return true
+	case "j", "down":
+		m.move(1)
+	case "k", "up":
+		m.move(-1)
+	case "pgdown", " ":
+		m.move(pageSize)
+	case "pgup":
+		m.move(-pageSize)
+	case "g", "home":
+		m.move(-len(m.visible))
+	case "G", "end":
+		m.move(len(m.visible))
+	case "s":
+		m.strategy = (m.strategy + 1) % len(m.strategies)
+		m.update()
+	case "r", "R":
+		// Cycle through all regions together, then each region; R goes back
+		positions := len(m.regions) + 1
+		step := 1
+		if key == "R" {
+			step = positions - 1
+		}
+		m.region = (m.region+1+step)%positions - 1
+		m.update()
+	case "/":
+		m.editing = true
+	case "esc":
+		m.filter = ""
+		m.update()
+	}
+	return false
+}
+
+// render draws the screen for a terminal of width columns and height rows
+func (m *tuiModel) render(out io.Writer, width, height int, color bool) {
+	region := "all regions"
+	if m.region >= 0 {
+		region = m.regions[m.region]
+	}
+	filter := m.filter
+	if m.editing {
+		filter += "_"
+	}
+	position := m.cursor + 1
+	if len(m.visible) == 0 {
+		position = 0
+	}
+	status := fmt.Sprintf("%s | sort: %s | filter: %s | %d of %d", region, m.strategies[m.strategy], filter, position, len(m.visible))
+
+	// Scroll the cursor into view below the status line and table header
+	pageSize := tuiPageSize(height)
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	}
+	if m.cursor >= m.offset+pageSize {
+		m.offset = m.cursor - pageSize + 1
+	}
+
+	var table bytes.Buffer
+	w := tabwriter.NewWriter(&table, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  REGION\tINSTANCE TYPE\tVCPUS\tMEMORY\tSPOT PRICE\tPRICE PER VCPU\tSAVINGS\tINTERRUPTION")
+	end := m.offset + pageSize
+	if end > len(m.visible) {
+		end = len(m.visible)
+	}
+	for _, row := range m.visible[m.offset:end] {
+		price, _ := strconv.ParseFloat(row.SpotPrice, 64)
+		fmt.Fprintf(w, "  %s\t%s\t%d\t%s\t$%s\t$%.6f\t%s\t%s\n", row.Region, row.InstanceType, row.VCPUS, row.Memory, row.SpotPrice, price/float64(row.VCPUS), row.SpotSavingRate, row.InterruptionRate)
+	}
+	w.Flush()
+
+	fmt.Fprint(out, ansiClear)
+	fmt.Fprintln(out, truncate(status, width))
+	for i, line := range strings.Split(strings.TrimSuffix(table.String(), "\n"), "\n") {
+		line = truncate(line, width)
+		if i > 0 && m.offset+i-1 == m.cursor {
+			if color {
+				line = ansiReverse + line + ansiReset
+			} else {
+				line = ">" + line[1:]
+			}
+		}
+		fmt.Fprintln(out, line)
+	}
+	fmt.Fprint(out, truncate(tuiHelp, width))
+}
+
+// tuiPageSize is the number of table rows fitting a terminal of height rows,
+// below the status line and table header and above the help line
+func tuiPageSize(height int) int {
+	if height <= 4 {
+		return 1
+	}
+	return height - 3
+}
+
+// readKeys splits terminal input into key names: printable characters are
+// their own name, escape sequences of the arrow and paging keys are named
+func readKeys(input []byte) []string {
+	sequences := map[string]string{
+		"\033[A": "up", "\033[B": "down", "\033[5~": "pgup", "\033[6~": "pgdown",
+		"\033[H": "home", "\033[F": "end", "\033OA": "up", "\033OB": "down",
+	}
+	var keys []string
+	for len(input) > 0 {
+		if input[0] == '\033' {
+			matched := false
+			for sequence, name := range sequences {
+				if bytes.HasPrefix(input, []byte(sequence)) {
+					keys, input, matched = append(keys, name), input[len(sequence):], true
+					break
+				}
+			}
+			if !matched {
+				keys, input = append(keys, "esc"), input[1:]
+			}
+			continue
+		}
+		switch c := input[0]; c {
+		case '\r', '\n':
+			keys = append(keys, "enter")
+		case 127, '\b':
+			keys = append(keys, "backspace")
+		case 3:
+			keys = append(keys, "ctrl-c")
+		default:
+			if c >= ' ' && c < 127 {
+				keys = append(keys, string(c))
+			}
+		}
+		input = input[1:]
+	}
+	return keys
+}
+
+// loadTUIData reads the dataset from a file or an http(s) URL
+func loadTUIData(source string) (SpotData, error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		return readExistingData(source)
+	}
+	resp, err := http.Get(source)
+	if err != nil {
+		return SpotData{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return SpotData{}, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	var data SpotData
+	err = json.NewDecoder(newLimitedReader(resp.Body, maxResponseBytes)).Decode(&data)
+	return data, err
+}
+
+// stty runs stty on the terminal and returns its output
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+// terminalSize returns the terminal's columns and rows, 80x24 when unknown
+func terminalSize() (int, int) {
+	size, err := stty("size")
+	if err == nil {
+		if rows, columns, ok := strings.Cut(size, " "); ok {
+			width, err1 := strconv.Atoi(columns)
+			height, err2 := strconv.Atoi(rows)
+			if err1 == nil && err2 == nil && width > 0 && height > 0 {
+				return width, height
+			}
+		}
+	}
+	return 80, 24
+}
+
+// runTUI implements the tui command: an interactive table of the published
+// deals that can be scrolled, sorted by any ranking strategy and filtered
+// by region and instance type
+func runTUI(args []string) {
+	tuiFlags.Parse(args)
+
+	data, err := loadTUIData(*tuiData)
+	if err != nil {
+		log.Fatalf("Error loading %s: %v", *tuiData, err)
+	}
+	m := newTUIModel(data, *tuiRegion)
+
+	// Put the terminal in raw mode to get keys as they are pressed, and
+	// restore it however the tui ends
+	saved, err := stty("-g")
+	if err != nil {
+		log.Fatal("tui needs an interactive terminal")
+	}
+	if _, err := stty("raw", "-echo"); err != nil {
+		log.Fatalf("Error setting up the terminal: %v", err)
+	}
+	fmt.Print(ansiAltScreen)
+	defer func() {
+		fmt.Print(ansiMainScreen)
+		stty(saved)
+	}()
+
+	// Raw mode does not turn \n into \r\n
+	screen := newlineWriter{os.Stdout}
+	color := os.Getenv("NO_COLOR") == ""
+	input := make([]byte, 64)
+	for {
+		width, height := terminalSize()
+		var frame bytes.Buffer
+		m.render(&frame, width, height, color)
+		screen.Write(frame.Bytes())
+
+		n, err := os.Stdin.Read(input)
+		if err != nil {
+			return
+		}
+		for _, key := range readKeys(input[:n]) {
+			if m.handleKey(key, tuiPageSize(height)) {
+				return
+			}
+		}
+	}
+}
+
+// newlineWriter writes \r\n for every \n, for terminals in raw mode
+type newlineWriter struct {
+	io.Writer
+}
+
+func (w newlineWriter) Write(p []byte) (int, error) {
+	_, err := w.Writer.Write(bytes.ReplaceAll(p, []byte("\n"), []byte("\r\n")))
+	return len(p), err
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadKeys(t *testing.T) {
+	got := readKeys([]byte("j\033[Bs/c6\177\r\033q\003"))
+	want := []string{"j", "down", "s", "/", "c", "6", "backspace", "enter", "esc", "q", "ctrl-c"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("readKeys = %q, want %q", got, want)
+	}
+}
+
+func TestTUIModel(t *testing.T) {
+	m := newTUIModel(readFixture(t), "")
+	visibleTypes := func() []string {
+		var types []string
+		for _, row := range m.visible {
+			types = append(types, row.Region+"/"+row.InstanceType)
+		}
+		return types
+	}
+	press := func(keys string) {
+		for _, key := range readKeys([]byte(keys)) {
+			if m.handleKey(key, 10) {
+				t.Fatalf("key %q quit", key)
+			}
+		}
+	}
+
+	if len(m.visible) != 12 || m.strategies[m.strategy] != "cheapest_per_vcpu" {
+		t.Fatalf("starts with %d rows sorted by %s", len(m.visible), m.strategies[m.strategy])
+	}
+	for i := 1; i < len(m.visible); i++ {
+		if pricePerVCPU(m.visible[i].Instance) < pricePerVCPU(m.visible[i-1].Instance) {
+			t.Errorf("rows not sorted by price per vCPU: %v", visibleTypes())
+		}
+	}
+
+	// Filter by instance type, then narrow down to a region
+	press("/c6g\r")
+	for _, row := range m.visible {
+		if !strings.Contains(row.InstanceType, "c6g") {
+			t.Errorf("filter kept %s", row.InstanceType)
+		}
+	}
+	press("r")
+	if want := []string{"ap-south-1/c6g.8xlarge"}; !reflect.DeepEqual(visibleTypes(), want) {
+		t.Errorf("ap-south-1 rows = %v, want %v", visibleTypes(), want)
+	}
+
+	// R goes back to every region, Esc clears the filter
+	press("R\033")
+	if len(m.visible) != 12 {
+		t.Errorf("got %d rows after clearing, want 12", len(m.visible))
+	}
+
+	// The cursor stays on the table and the screen scrolls to it
+	press("GGj")
+	if m.cursor != 11 {
+		t.Errorf("cursor = %d, want 11", m.cursor)
+	}
+	var screen bytes.Buffer
+	m.render(&screen, 200, 8, false)
+	lines := strings.Split(screen.String(), "\n")
+	if len(lines) != 8 || !strings.HasPrefix(lines[6], ">") || !strings.Contains(lines[0], "12 of 12") {
+		t.Errorf("unexpected screen:\n%s", screen.String())
+	}
+
+	if !m.handleKey("q", 10) {
+		t.Error("q did not quit")
+	}
+}
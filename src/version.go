@@ -0,0 +1,161 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+)
+
+// Build metadata, set at link time with -ldflags "-X main.version=v1.2.3
+// -X main.gitCommit=$(git rev-parse HEAD) -X main.buildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var (
+	version   = "dev"
+	gitCommit string
+	buildDate string
+)
+
+// BuildInfo describes the running binary
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"buildDate"`
+	GoVersion string `json:"goVersion"`
+}
+
+// buildInfo returns the linked-in build metadata, falling back to the VCS
+// stamp the Go toolchain embeds when building from a checkout
+func buildInfo() BuildInfo {
+	info := BuildInfo{Version: version, Commit: gitCommit, BuildDate: buildDate, GoVersion: runtime.Version()}
+	if embedded, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range embedded.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = setting.Value
+			case setting.Key == "vcs.time" && info.BuildDate == "":
+				info.BuildDate = setting.Value
+			}
+		}
+	}
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+	if info.BuildDate == "" {
+		info.BuildDate = "unknown"
+	}
+	return info
+}
+
+// upstreamRepo publishes the tool's releases, tagged vMAJOR.MINOR.PATCH
+// next to the dated spot-data snapshots
+const upstreamRepo = "fjcloud/ec2-spot-finder-static"
+
+var (
+	versionFlags = flag.NewFlagSet("version", flag.ExitOnError)
+	versionCheck = versionFlags.Bool("check", false, "compare with the latest GitHub release and print upgrade instructions")
+)
+
+// runVersion implements the version command
+func runVersion(args []string) {
+	versionFlags.Parse(args)
+
+	info := buildInfo()
+	fmt.Printf("%s %s (commit %s, built %s, %s)\n", programName, info.Version, info.Commit, info.BuildDate, info.GoVersion)
+	if !*versionCheck {
+		return
+	}
+
+	latest, err := latestToolRelease(&GitHubClient{Token: os.Getenv("GITHUB_TOKEN"), Repo: upstreamRepo})
+	if err != nil {
+		log.Fatalf("Error checking for updates: %v", err)
+	}
+	current, ok := parseSemver(info.Version)
+	switch {
+	case !ok:
+		fmt.Printf("This is a development build; the latest release is %s\n", latest.TagName)
+	case !semverLess(current, latest.version):
+		fmt.Println("You are running the latest release.")
+		return
+	default:
+		fmt.Printf("A newer release is available: %s\n", latest.TagName)
+	}
+	fmt.Printf("  %s\n\n", latest.HTMLURL)
+	fmt.Println("Download a binary from the release page, or rebuild from a checkout:")
+	fmt.Printf("  git fetch --tags && git checkout %s\n", latest.TagName)
+	fmt.Printf("  go build -ldflags \"-X main.version=%s\" -o %s src/*.go\n", latest.TagName, programName)
+}
+
+// toolRelease is a published release of the tool
+type toolRelease struct {
+	TagName    string `json:"tag_name"`
+	HTMLURL    string `json:"html_url"`
+	Draft      bool   `json:"draft"`
+	Prerelease bool   `json:"prerelease"`
+
+	version [3]int
+}
+
+// latestToolRelease returns the highest version among the repository's
+// releases, skipping data snapshots, drafts and prereleases
+func latestToolRelease(client *GitHubClient) (toolRelease, error) {
+	var releases []toolRelease
+	if err := client.do("GET", "/repos/"+client.Repo+"/releases?per_page=100", nil, &releases); err != nil {
+		return toolRelease{}, err
+	}
+
+	var latest toolRelease
+	found := false
+	for _, release := range releases {
+		tagged, ok := parseSemver(release.TagName)
+		if !ok || release.Draft || release.Prerelease {
+			continue
+		}
+		if !found || semverLess(latest.version, tagged) {
+			release.version = tagged
+			latest, found = release, true
+		}
+	}
+	if !found {
+		return toolRelease{}, errors.New("no release of the tool found")
+	}
+	return latest, nil
+}
+
+// parseSemver parses a "v1.2.3" tag, ignoring any pre-release or build suffix
+func parseSemver(tag string) ([3]int, bool) {
+	var parsed [3]int
+	if !strings.HasPrefix(tag, "v") {
+		return parsed, false
+	}
+	core := strings.TrimPrefix(tag, "v")
+	if i := strings.IndexAny(core, "-+"); i >= 0 {
+		core = core[:i]
+	}
+	parts := strings.Split(core, ".")
+	if len(parts) != 3 {
+		return parsed, false
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return parsed, false
+		}
+		parsed[i] = n
+	}
+	return parsed, true
+}
+
+// semverLess reports whether version a precedes b
+func semverLess(a, b [3]int) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return false
+}
@@ -0,0 +1,17 @@
+#!/bin/sh
+# Builds the query engine for the site: query.wasm and the wasm_exec.js
+# loader matching the Go version, written to the directory given ($1).
+# Run through go generate src/main.go.
+set -e
+out=$(cd "$1" && pwd)
+dir=$(mktemp -d)
+trap 'rm -rf "$dir"' EXIT
+
+cp *.go "$dir"
+rm "$dir/main.go" "$dir"/*_test.go
+cp wasm/query.go "$dir"
+cp -r frontend locations.json "$dir"
+(cd "$dir" && GOOS=js GOARCH=wasm go build -trimpath -ldflags='-s -w' -o "$out/query.wasm" *.go)
+
+root=$(go env GOROOT)
+cp "$root/lib/wasm/wasm_exec.js" "$out" 2>/dev/null || cp "$root/misc/wasm/wasm_exec.js" "$out"
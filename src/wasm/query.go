@@ -0,0 +1,55 @@
+// This file is not part of the CLI: wasm/build.sh compiles it with the
+// package's other files, minus main.go, into the site's query.wasm.
+
+//go:build js && wasm
+
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"syscall/js"
+)
+
+// main is the entry point of the WebAssembly build. It exposes the query
+// engine of POST /api/query to the site, so filtering in the browser uses
+// the same logic as the API and the CLI:
+//
+//	spotLoad(dataJSON)   loads spot_data.json, returning an error message or null
+//	spotQuery(queryJSON) returns the QueryResponse, or {"error": ...}, as JSON
+func main() {
+	var data SpotData
+	js.Global().Set("spotLoad", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		if len(args) != 1 {
+			return "spotLoad expects the dataset as JSON"
+		}
+		var loaded SpotData
+		if err := json.Unmarshal([]byte(args[0].String()), &loaded); err != nil {
+			return err.Error()
+		}
+		data = loaded
+		return nil
+	}))
+	js.Global().Set("spotQuery", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		if len(args) != 1 {
+			return wasmJSON(map[string]string{"error": "spotQuery expects a query as JSON"})
+		}
+		query, err := decodeQuery(strings.NewReader(args[0].String()))
+		if err != nil {
+			return wasmJSON(map[string]string{"error": err.Error()})
+		}
+		return wasmJSON(query.respond(data))
+	}))
+
+	// Keep the functions callable
+	select {}
+}
+
+// wasmJSON encodes a value returned to JavaScript
+func wasmJSON(v interface{}) string {
+	content, err := json.Marshal(v)
+	if err != nil {
+		return `{"error":"encoding the response failed"}`
+	}
+	return string(content)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+var (
+	watchFlags    = flag.NewFlagSet("watch", flag.ExitOnError)
+	watchRegion   = watchFlags.String("region", "", "AWS region to watch (e.g. eu-west-1)")
+	watchInterval = watchFlags.Duration("interval", 5*time.Minute, "time between refreshes")
+)
+
+// ANSI escape sequences used to render the watch screen
+const (
+	ansiClear = "\033[H\033[2J"
+	ansiRed   = "\033[31m"
+	ansiGreen = "\033[32m"
+	ansiReset = "\033[0m"
+)
+
+// runWatch implements the watch command: it periodically refreshes a
+// region's deals and highlights price changes since the previous refresh
+func runWatch(args []string) {
+	watchFlags.Parse(args)
+
+	if *watchRegion == "" || *watchInterval <= 0 {
+		fmt.Fprintln(os.Stderr, "usage: watch --region <region> [--interval 5m]")
+		watchFlags.PrintDefaults()
+		os.Exit(2)
+	}
+
+	// Honor the NO_COLOR convention for terminals without ANSI support
+	color := os.Getenv("NO_COLOR") == ""
+
+	var previous map[string]string
+	ticker := time.NewTicker(*watchInterval)
+	defer ticker.Stop()
+
+	for {
+		deals, err := getSpotDeals(*watchRegion)
+		if err != nil {
+			log.Printf("Error getting spot deals for region %s: %v", *watchRegion, err)
+		} else {
+			prices := make(map[string]string, len(deals))
+			for _, instance := range deals {
+				prices[instance.InstanceType] = instance.SpotPrice
+			}
+
+			fmt.Print(ansiClear)
+			fmt.Printf("Spot deals in %s, refreshed %s (every %s)\n\n", *watchRegion, time.Now().Format("15:04:05"), *watchInterval)
+			printInstanceTable(os.Stdout, deals, func(instance Instance) string {
+				return priceChangeNote(previous, instance, color)
+			})
+			previous = prices
+		}
+		<-ticker.C
+	}
+}
+
+// priceChangeNote describes how an instance's price moved since the previous refresh
+func priceChangeNote(previous map[string]string, instance Instance, color bool) string {
+	if previous == nil {
+		return ""
+	}
+	oldPrice, ok := previous[instance.InstanceType]
+	if !ok {
+		return "new"
+	}
+
+	oldValue, err1 := strconv.ParseFloat(oldPrice, 64)
+	newValue, err2 := strconv.ParseFloat(instance.SpotPrice, 64)
+	if err1 != nil || err2 != nil || oldValue == newValue {
+		return ""
+	}
+
+	note := fmt.Sprintf("▲ +%.4f", newValue-oldValue)
+	code := ansiRed
+	if newValue < oldValue {
+		note = fmt.Sprintf("▼ -%.4f", oldValue-newValue)
+		code = ansiGreen
+	}
+	if !color {
+		return note
+	}
+	return code + note + ansiReset
+}